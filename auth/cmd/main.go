@@ -11,9 +11,17 @@ import (
 	"auth/config"
 	"auth/internal/handler"
 	"auth/internal/middleware"
+	"auth/internal/models"
 	"auth/internal/repository"
 	"auth/internal/service"
+	"auth/internal/worker"
 	authdb "auth/pkg/database"
+	"auth/pkg/hasher"
+	"auth/pkg/jwk"
+	"auth/pkg/mailer"
+	"auth/pkg/oauth"
+	"auth/pkg/passkey"
+	"auth/pkg/password"
 	"auth/pkg/rabbitmq"
 	"auth/pkg/redis"
 	"context"
@@ -26,6 +34,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	_ "auth/docs"
 
@@ -35,14 +44,26 @@ import (
 
 func main() {
 	config.InitEnv()
+	jwk.InitKeys()
 	rdb := redis.InitAuthRedis()
 	authdb.InitDB()
 	rabbitmq.InitRabbitMQ()
 
 	authRepo := repository.NewAuthRepository(authdb.GetDB())
-	authService := service.NewAuthService(authRepo)
+	passwordHasher := hasher.InitHasher()
+	authService := service.NewAuthService(authRepo, mailer.InitMailer(), passkey.InitWebAuthn(), oauth.InitProviders(), password.InitChecker(passwordHasher.MaxPasswordLength()), passwordHasher)
 	authHandler := handler.NewAuthHandler(authService)
 
+	if err := authService.EnsureAdmins(); err != nil {
+		log.Printf("[Admin] Failed to grant admin role from ADMIN_EMAILS: %v", err)
+	}
+	if err := authService.SyncSuspensions(); err != nil {
+		log.Printf("[Admin] Failed to sync suspensions to Redis: %v", err)
+	}
+	if err := authService.SyncAPITokens(); err != nil {
+		log.Printf("[Tokens] Failed to sync API tokens to Redis: %v", err)
+	}
+
 	r := gin.Default()
 	r.ForwardedByClientIP = true
 	api := r.Group("/api")
@@ -54,7 +75,21 @@ func main() {
 		sensitive.POST("/auth/login", authHandler.Login)
 		sensitive.POST("/auth/verify", authHandler.VerifyOTP)
 		sensitive.POST("/auth/refresh", authHandler.Refresh)
-		sensitive.POST("/auth/resend", authHandler.ResendOTP)
+		// Сверх лимита по IP — не больше 3 писем в 10 минут на одного пользователя. Ключ — разобранный UUID:
+		// иначе другое написание того же id (регистр, фигурные скобки, urn:uuid:) давало бы новый лимит
+		sensitive.POST("/auth/resend", middleware.KeyedRateLimiterMiddleware(rdb, "3-10M", "auth:limiter:resend:", func(c *gin.Context) string {
+			id, err := uuid.Parse(c.Query("id"))
+			if err != nil {
+				return c.ClientIP()
+			}
+			return id.String()
+		}), authHandler.ResendOTP)
+		sensitive.POST("/auth/magic-link/verify", authHandler.VerifyMagicLink)
+		sensitive.POST("/auth/passkeys/login/begin", authHandler.BeginPasskeyLogin)
+		sensitive.POST("/auth/passkeys/login/finish", authHandler.FinishPasskeyLogin)
+		sensitive.GET("/auth/oauth/:provider/login", authHandler.OAuthLogin)
+		sensitive.GET("/auth/oauth/:provider/callback", authHandler.OAuthCallback)
+		sensitive.POST("/auth/oauth/2fa", authHandler.OAuthTwoFactor)
 	}
 
 	resetGroup := api.Group("")
@@ -63,21 +98,73 @@ func main() {
 		resetGroup.POST("/auth/forgot-password", authHandler.ForgotPassword)
 		resetGroup.POST("/auth/reset-password", authHandler.ResetPassword)
 		resetGroup.POST("/auth/delete/cancel", authHandler.DeleteCancel)
+		resetGroup.POST("/auth/magic-link", authHandler.RequestMagicLink)
 	}
 
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(authService))
 	{
 		protected.GET("/auth/me", authHandler.Me)
 		protected.GET("/auth/sessions", authHandler.ListSessions)
+		protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+		protected.GET("/auth/trusted-devices", authHandler.ListTrustedDevices)
+		protected.DELETE("/auth/trusted-devices", authHandler.RevokeAllTrustedDevices)
+		protected.DELETE("/auth/trusted-devices/:id", authHandler.RevokeTrustedDevice)
+		protected.GET("/auth/security-events", authHandler.ListSecurityEvents)
+		protected.POST("/auth/password", authHandler.ChangePassword)
+		protected.POST("/auth/email", authHandler.ChangeEmail)
+		protected.POST("/auth/email/confirm", authHandler.ConfirmEmailChange)
 
 		protected.POST("/auth/delete", authHandler.Delete)
 		protected.POST("/auth/delete/confirm", authHandler.DeleteConfirm)
 
 		protected.POST("/auth/logout", authHandler.LogoutCurrent)
 		protected.POST("/auth/logout/all", authHandler.LogoutAll)
+
+		protected.POST("/auth/2fa/enroll", authHandler.EnrollTOTP)
+		protected.POST("/auth/2fa/confirm", authHandler.ConfirmTOTP)
+		protected.POST("/auth/2fa/disable", authHandler.DisableTOTP)
+		protected.POST("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+
+		protected.GET("/auth/passkeys", authHandler.ListPasskeys)
+		protected.POST("/auth/passkeys/register/begin", authHandler.BeginPasskeyRegistration)
+		protected.POST("/auth/passkeys/register/finish", authHandler.FinishPasskeyRegistration)
+		protected.DELETE("/auth/passkeys/:id", authHandler.DeletePasskey)
+
+		protected.GET("/auth/oauth/identities", authHandler.ListIdentities)
+		protected.GET("/auth/oauth/:provider/link", authHandler.OAuthLink)
+		protected.DELETE("/auth/oauth/:provider", authHandler.UnlinkIdentity)
+
+		protected.POST("/auth/bots", authHandler.CreateBot)
+		protected.GET("/auth/bots", authHandler.ListBots)
+		protected.DELETE("/auth/bots/:id", authHandler.DeleteBot)
+		protected.POST("/auth/tokens", authHandler.CreateAPIToken)
+		protected.GET("/auth/tokens", authHandler.ListAPITokens)
+		protected.DELETE("/auth/tokens/:id", authHandler.RevokeAPIToken)
+	}
+
+	admin := api.Group("/auth/admin")
+	admin.Use(middleware.AuthMiddleware(authService), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/users", authHandler.SearchUsers)
+		admin.POST("/users/:id/verify", authHandler.AdminVerifyUser)
+		admin.POST("/users/:id/suspend", authHandler.AdminSuspendUser)
+		admin.POST("/users/:id/unsuspend", authHandler.AdminUnsuspendUser)
+		admin.POST("/users/:id/logout", authHandler.AdminForceLogout)
+		admin.POST("/users/:id/deletion", authHandler.AdminScheduleDeletion)
+		admin.DELETE("/users/:id/deletion", authHandler.AdminCancelDeletion)
+		admin.PUT("/users/:id/role", authHandler.AdminSetRole)
+		admin.GET("/audit", authHandler.ListAuditEntries)
+	}
+
+	// Внутренние сервисы авторизуются своим id и секретом, без пользовательских токенов
+	internal := api.Group("")
+	{
+		internal.POST("/auth/oauth/token", authHandler.ServiceToken)
+		internal.POST("/auth/introspect", authHandler.Introspect)
 	}
 
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	port := config.Env.AppPort
@@ -94,12 +181,17 @@ func main() {
 		}
 	}()
 
+	// Фоновое удаление аккаунтов после истечения срока восстановления
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	go worker.StartDeletionWorker(workerCtx, authService, config.Env.DeletionWorkerInterval)
+
 	// Блокируем main, ждём сигнал завершения
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Println("[Shutting down]")
+	stopWorker()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -3,16 +3,44 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// OAuthProvider — настройки внешнего OIDC-провайдера; адреса задаются явно, чтобы можно было подставить mock-сервер
+type OAuthProvider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// ServiceClient — внутренний сервис, получающий токены по client credentials и вызывающий introspection
+type ServiceClient struct {
+	ID     string
+	Secret string
+	Scopes []string
+}
+
 type AuthConfig struct {
-	JWTSecret            []byte
+	JWTKeysDir           string
+	JWTActiveKID         string
+	JWTEphemeralKeys     bool // только для разработки: без JWT_KEYS_DIR сгенерировать временный ключ
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
 
+	DeletionWorkerInterval time.Duration
+
+	TOTPIssuer        string
+	TOTPEncryptionKey []byte
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -23,6 +51,46 @@ type AuthConfig struct {
 	AppPort      string
 	RedisAddr    string
 	RabbitMQAddr string
+
+	MagicLinkURL string
+
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	OAuthProviders []OAuthProvider
+
+	ServiceClients       []ServiceClient
+	ServiceTokenDuration time.Duration
+
+	AdminEmails []string
+
+	PasswordMinLength     int
+	PasswordRequireLower  bool
+	PasswordRequireUpper  bool
+	PasswordRequireLetter bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordHistorySize   int
+	PasswordBreachedCheck bool
+	PasswordBreachedFile  string // пусто — встроенный список
+
+	TrustedDeviceDuration time.Duration
+
+	PasswordHashAlgorithm string // bcrypt | argon2id
+	BcryptCost            int
+	Argon2Memory          int // КиБ
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	Argon2MaxConcurrent   int // сколько хешей argon2id считается одновременно
+
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
 }
 
 var Env *AuthConfig
@@ -33,11 +101,19 @@ func InitEnv() {
 	}
 
 	Env = &AuthConfig{
-		JWTSecret: []byte(os.Getenv("JWT_SECRET")),
+		JWTKeysDir:   os.Getenv("JWT_KEYS_DIR"),
+		JWTActiveKID: os.Getenv("JWT_ACTIVE_KID"),
+
+		JWTEphemeralKeys: getBool("JWT_EPHEMERAL_KEYS", false),
 
 		AccessTokenDuration:  getDuration("JWT_ACCESS_DURATION", 15*time.Minute),
 		RefreshTokenDuration: getDuration("JWT_REFRESH_DURATION", 30*24*time.Hour),
 
+		DeletionWorkerInterval: getDuration("DELETION_WORKER_INTERVAL", time.Hour),
+
+		TOTPIssuer:        getString("TOTP_ISSUER", "LiveChat"),
+		TOTPEncryptionKey: []byte(os.Getenv("TOTP_ENCRYPTION_KEY")),
+
 		DBHost:     os.Getenv("DB_HOST"),
 		DBPort:     os.Getenv("DB_PORT"),
 		DBUser:     os.Getenv("DB_USER"),
@@ -48,6 +124,46 @@ func InitEnv() {
 		AppPort:      os.Getenv("PORT_AUTH"),
 		RedisAddr:    os.Getenv("REDIS_ADDR"),
 		RabbitMQAddr: os.Getenv("RABBITMQ_ADDR"),
+
+		MagicLinkURL: getString("MAGIC_LINK_URL", "http://localhost:5173/auth/magic"),
+
+		WebAuthnRPID:    getString("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:  getString("WEBAUTHN_RP_NAME", "LiveChat"),
+		WebAuthnOrigins: strings.Split(getString("WEBAUTHN_ORIGINS", "http://localhost:5173"), ","),
+
+		OAuthProviders: getOAuthProviders(),
+
+		ServiceClients:       getServiceClients(),
+		ServiceTokenDuration: getDuration("SERVICE_TOKEN_DURATION", 15*time.Minute),
+
+		AdminEmails: getList("ADMIN_EMAILS"),
+
+		PasswordMinLength:     getInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireLower:  getBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireUpper:  getBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLetter: getBool("PASSWORD_REQUIRE_LETTER", true),
+		PasswordRequireDigit:  getBool("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSymbol: getBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordHistorySize:   getInt("PASSWORD_HISTORY_SIZE", 5),
+		PasswordBreachedCheck: getBool("PASSWORD_BREACHED_CHECK", true),
+		PasswordBreachedFile:  os.Getenv("PASSWORD_BREACHED_FILE"),
+
+		TrustedDeviceDuration: getDuration("TRUSTED_DEVICE_DURATION", 30*24*time.Hour),
+
+		PasswordHashAlgorithm: getString("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost:            getInt("BCRYPT_COST", 10),
+		Argon2Memory:          getInt("ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:      getInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:     getInt("ARGON2_PARALLELISM", 2),
+		Argon2MaxConcurrent:   getInt("ARGON2_MAX_CONCURRENT", 4),
+
+		MailDriver:   getString("MAIL_DRIVER", "console"),
+		MailFrom:     getString("MAIL_FROM", "no-reply@livechat.local"),
+		MailDir:      os.Getenv("MAIL_DIR"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getString("SMTP_PORT", "587"),
+		SMTPUser:     os.Getenv("SMTP_USER"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 	}
 }
 
@@ -60,3 +176,114 @@ func getDuration(key string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+func getInt(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("Invalid integer for %s, using fallback", key)
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("Invalid boolean for %s, using fallback", key)
+	}
+	return fallback
+}
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getList читает значения через запятую, пропуская пустые
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Известные провайдеры с адресами по умолчанию; любой из них можно переопределить через env
+var oauthDefaults = map[string]OAuthProvider{
+	"google": {
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+	},
+}
+
+// getOAuthProviders читает OAUTH_PROVIDERS=google,mock и для каждого OAUTH_<NAME>_CLIENT_ID, _CLIENT_SECRET, _AUTH_URL, _TOKEN_URL, _USERINFO_URL, _SCOPES
+func getOAuthProviders() []OAuthProvider {
+	names := os.Getenv("OAUTH_PROVIDERS")
+	if names == "" {
+		return nil
+	}
+
+	redirectBase := getString("OAUTH_REDIRECT_BASE_URL", "http://localhost:"+os.Getenv("PORT_AUTH")+"/api/auth/oauth")
+	var providers []OAuthProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		defaults := oauthDefaults[name]
+
+		p := OAuthProvider{
+			Name:         name,
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			AuthURL:      getString(prefix+"AUTH_URL", defaults.AuthURL),
+			TokenURL:     getString(prefix+"TOKEN_URL", defaults.TokenURL),
+			UserInfoURL:  getString(prefix+"USERINFO_URL", defaults.UserInfoURL),
+			RedirectURL:  getString(prefix+"REDIRECT_URL", redirectBase+"/"+name+"/callback"),
+			Scopes:       strings.Split(getString(prefix+"SCOPES", "openid,email,profile"), ","),
+		}
+		if p.ClientID == "" || p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "" {
+			log.Printf("OAuth provider %s is not fully configured, skipping", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// getServiceClients читает SERVICE_CLIENTS=user,chat и для каждого SERVICE_CLIENT_<ID>_SECRET, _SCOPES
+func getServiceClients() []ServiceClient {
+	ids := os.Getenv("SERVICE_CLIENTS")
+	if ids == "" {
+		return nil
+	}
+
+	var clients []ServiceClient
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		prefix := "SERVICE_CLIENT_" + strings.ToUpper(id) + "_"
+		client := ServiceClient{
+			ID:     id,
+			Secret: os.Getenv(prefix + "SECRET"),
+			Scopes: strings.Split(getString(prefix+"SCOPES", "auth:introspect"), ","),
+		}
+		if client.Secret == "" {
+			log.Printf("Service client %s has no secret, skipping", id)
+			continue
+		}
+		clients = append(clients, client)
+	}
+	return clients
+}
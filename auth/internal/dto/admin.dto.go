@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AdminUserResponse struct {
+	ID               uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Email            string     `json:"email" example:"user@example.com"`
+	IsVerified       bool       `json:"is_verified" example:"true"`
+	Role             string     `json:"role" example:"user"`
+	TOTPEnabled      bool       `json:"totp_enabled" example:"false"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty" example:"2026-01-20T18:02:00Z"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty" example:"2026-02-01T00:00:00Z"`
+	SuspensionReason string     `json:"suspension_reason,omitempty" example:"spam"`
+	ToBeDeletedAt    *time.Time `json:"to_be_deleted_at,omitempty" example:"2026-01-23T18:02:00Z"`
+	CreatedAt        time.Time  `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
+
+type AdminUsersResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	Total int64               `json:"total" example:"42"`
+}
+
+type SuspendRequest struct {
+	Reason string     `json:"reason" binding:"required,max=255" example:"spam"`
+	Until  *time.Time `json:"until" example:"2026-02-01T00:00:00Z"` // пусто — бессрочно
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin" example:"moderator"`
+}
+
+type AdminDeletionResponse struct {
+	DeletionAt time.Time `json:"deletion_at" example:"2026-01-23T18:02:00Z"`
+}
+
+type AuditEntryResponse struct {
+	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ActorID   uuid.UUID `json:"actor_id" example:"8c1b7a52-3f0e-4d6a-9b61-2f4a0c1e7d90"`
+	TargetID  uuid.UUID `json:"target_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Action    string    `json:"action" example:"suspend"`
+	Details   string    `json:"details,omitempty" example:"user -> moderator"`
+	IP        string    `json:"ip" example:"85.145.12.34"`
+	CreatedAt time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
+
+type AuditEntriesResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+	Total   int64                `json:"total" example:"42"`
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateBotRequest struct {
+	Name string `json:"name" binding:"required,min=2,max=50"`
+}
+
+type CreateAPITokenRequest struct {
+	Name          string     `json:"name" binding:"required,max=100"`
+	Scopes        []string   `json:"scopes" binding:"required,min=1" example:"user:read,chat:write"`
+	ExpiresInDays int        `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 0 — бессрочный
+	BotID         *uuid.UUID `json:"bot_id"`                                            // выпустить токен для своего бота
+}
+
+type BotResponse struct {
+	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	CreatedAt time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
+
+type APITokenResponse struct {
+	ID         uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserID     uuid.UUID  `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name       string     `json:"name" example:"CI"`
+	Prefix     string     `json:"prefix" example:"lcp_Xk3v9a"`
+	Scopes     []string   `json:"scopes" example:"user:read,chat:write"`
+	ExpiresAt  *time.Time `json:"expires_at" example:"2026-06-16T09:17:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at" example:"2026-02-01T12:00:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
+
+type CreatedAPITokenResponse struct {
+	APITokenResponse
+	Token string `json:"token" example:"lcp_Xk3v9a..."` // показывается один раз
+}
@@ -6,18 +6,53 @@ import "github.com/google/uuid"
 
 type AuthRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // требования к новому паролю проверяет политика
 }
 
 type VerifyOTPRequest struct {
-	UserID uuid.UUID `json:"user_id" binding:"required"`
-	Code   string    `json:"code" binding:"required,len=6"`
-	Action string    `json:"action" binding:"required"`
+	UserID   uuid.UUID `json:"user_id" binding:"required"`
+	Code     string    `json:"code" binding:"required,len=6"`
+	Action   string    `json:"action" binding:"required"`
+	TOTPCode string    `json:"totp_code" binding:"omitempty,len=6"` // обязателен при входе, если включена 2FA
+
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=20"` // вместо totp_code, если нет доступа к аутентификатору
+
+	RememberDevice bool `json:"remember_device"` // при входе: следующие входы с этого браузера без email-OTP
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
 }
 
 type ResetPasswordRequest struct {
-	UserID      uuid.UUID `json:"user_id" binding:"required"`
-	NewPassword string    `json:"new_password" binding:"required,min=6"`
+	ResetToken  string `json:"reset_token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword     string `json:"current_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	OldCode string `json:"old_code" binding:"required,len=6"` // код, отправленный на текущую почту
+	NewCode string `json:"new_code" binding:"required,len=6"` // код, отправленный на новую почту
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token        string `json:"token" binding:"required"`
+	TOTPCode     string `json:"totp_code" binding:"omitempty,len=6"` // обязателен, если включена 2FA
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=20"`
 }
 
 type TempTokenRequest struct {
@@ -36,8 +71,20 @@ type AuthResponse struct {
 }
 
 type OTPSentResponse struct {
-	UserID  uuid.UUID `json:"user_id"`
-	Message string    `json:"message"`
+	UserID    uuid.UUID `json:"user_id"`
+	Message   string    `json:"message"`
+	TwoFactor bool      `json:"two_factor,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	Message string   `json:"message"`
+	Codes   []string `json:"codes" example:"k7m2p-x9qaz,4rtwe-hn3b8"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/LiveChat:user@example.com?issuer=LiveChat&secret=JBSWY3DPEHPK3PXP"`
+	QRCode string `json:"qr_code" example:"data:image/png;base64,iVBORw0KGgo..."`
 }
 
 type TempTokenResponse struct {
@@ -28,3 +28,28 @@ type ErrorResponse struct {
 	Code  int    `json:"code"`
 	Error string `json:"error"`
 }
+
+type LockedErrorResponse struct {
+	Code        int       `json:"code" example:"429"`
+	Error       string    `json:"error"`
+	LockedUntil time.Time `json:"locked_until" example:"2026-01-16T09:17:00Z"`
+}
+
+type SuspendedErrorResponse struct {
+	Code   int        `json:"code" example:"403"`
+	Error  string     `json:"error" example:"account suspended"`
+	Reason string     `json:"reason,omitempty" example:"spam"`
+	Until  *time.Time `json:"until,omitempty" example:"2026-02-01T00:00:00Z"`
+}
+
+type FieldViolation struct {
+	Field   string `json:"field" example:"password"`
+	Code    string `json:"code" example:"too_short"`
+	Message string `json:"message" example:"Пароль должен содержать не менее 8 символов"`
+}
+
+type PasswordPolicyErrorResponse struct {
+	Code       int              `json:"code" example:"400"`
+	Error      string           `json:"error" example:"password does not meet policy"`
+	Violations []FieldViolation `json:"violations"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+// Запросы и ответы в формате OAuth 2.0 (RFC 6749, RFC 7662): тело запроса — application/x-www-form-urlencoded
+
+type ServiceTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type ServiceTokenResponse struct {
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJFUzI1NiIs..."`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" example:"900"`
+	Scope       string `json:"scope" example:"auth:introspect"`
+}
+
+type IntrospectRequest struct {
+	Token        string `form:"token" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type IntrospectResponse struct {
+	Active    bool   `json:"active" example:"true"`
+	Subject   string `json:"sub,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Scope     string `json:"scope,omitempty" example:"user:read chat:read"`
+	SessionID string `json:"sid,omitempty" example:"8c1b7a52-3f0e-4d6a-9b61-2f4a0c1e7d90"`
+	Role      string `json:"role,omitempty" example:"user"`
+	TokenType string `json:"token_type,omitempty" example:"access"`
+	ClientID  string `json:"client_id,omitempty" example:"chat"`
+	ExpiresAt int64  `json:"exp,omitempty" example:"1768555020"`
+	Revoked   bool   `json:"revoked" example:"false"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OAuthTwoFactorRequest struct {
+	TempToken    string `json:"temp_token" binding:"required"`
+	TOTPCode     string `json:"totp_code" binding:"omitempty,len=6"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=20"`
+}
+
+type IdentityResponse struct {
+	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Provider  string    `json:"provider" example:"google"`
+	Email     string    `json:"email" example:"user@gmail.com"`
+	CreatedAt time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+)
+
+type PasskeyResponse struct {
+	ID             uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name           string     `json:"name" example:"MacBook Touch ID"`
+	Transports     []string   `json:"transports" example:"internal,hybrid"`
+	BackupEligible bool       `json:"backup_eligible" example:"true"`
+	CreatedAt      time.Time  `json:"created_at" example:"2026-01-16T09:17:00Z"`
+	LastUsedAt     *time.Time `json:"last_used_at" example:"2026-02-01T12:00:00Z"`
+}
+
+type PasskeyLoginOptionsResponse struct {
+	SessionID string                        `json:"session_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Options   *protocol.CredentialAssertion `json:"options"`
+}
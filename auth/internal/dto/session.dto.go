@@ -7,10 +7,43 @@ import (
 )
 
 type SessionResponse struct {
-	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Device    string    `json:"device" example:"Mobile / iOS 17 / Safari"`
-	IP        string    `json:"ip" example:"85.145.12.34"`
-	UserAgent string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)..."`
-	CreatedAt time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
-	ExpiresAt time.Time `json:"expires_at" example:"2026-02-16T09:17:00Z"`
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Device     string    `json:"device" example:"Mobile / iOS 17 / Safari"`
+	IP         string    `json:"ip" example:"85.145.12.34"`
+	LastIP     string    `json:"last_ip" example:"85.145.12.40"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)..."`
+	CreatedAt  time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2026-01-20T18:02:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2026-02-16T09:17:00Z"`
+	Current    bool      `json:"current" example:"true"`
+}
+
+type TrustedDeviceResponse struct {
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Device     string    `json:"device" example:"Desktop / macOS / Chrome"`
+	IP         string    `json:"ip" example:"85.145.12.34"`
+	LastIP     string    `json:"last_ip" example:"85.145.12.40"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)..."`
+	CreatedAt  time.Time `json:"created_at" example:"2026-01-16T09:17:00Z"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2026-01-20T18:02:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2026-02-15T09:17:00Z"`
+	Current    bool      `json:"current" example:"true"`
+}
+
+type SecurityEventResponse struct {
+	ID        uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Type      string     `json:"type" example:"login"`
+	IP        string     `json:"ip" example:"85.145.12.34"`
+	Device    string     `json:"device" example:"Desktop / Windows 10 / Chrome"`
+	UserAgent string     `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64)..."`
+	Details   string     `json:"details,omitempty" example:"all"`
+	NewDevice bool       `json:"new_device" example:"false"`
+	Count     int        `json:"count" example:"1"` // сколько раз повторилось (неудачные входы с одного IP за час)
+	LastSeen  *time.Time `json:"last_seen_at,omitempty" example:"2026-01-16T09:42:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2026-01-16T09:17:00Z"`
+}
+
+type SecurityEventsResponse struct {
+	Events []SecurityEventResponse `json:"events"`
+	Total  int64                   `json:"total" example:"42"`
 }
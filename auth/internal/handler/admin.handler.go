@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"auth/internal/dto"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ! Администрирование
+
+// adminTarget разбирает :id и id администратора; при ошибке сам отвечает клиенту
+func adminTarget(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	actorID := c.MustGet("userID").(uuid.UUID)
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid user id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return actorID, targetID, true
+}
+
+func adminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "user not found"})
+	case err.Error() == "cannot apply to own account" || err.Error() == "unknown role" || err.Error() == "bots cannot have roles" ||
+		err.Error() == "suspension end must be in the future":
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+	case err.Error() == "user is already verified" || err.Error() == "user is already suspended" || err.Error() == "user is not suspended" ||
+		err.Error() == "deletion is already scheduled" || err.Error() == "deletion is not scheduled":
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+	}
+}
+
+func pagination(c *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// SearchUsers
+// @Summary      Поиск пользователей
+// @Description  Только для администраторов. Ищет пользователей по части email без учёта регистра.
+// @Tags         admin
+// @Produce      json
+// @Param        email query string false "Часть email"
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Param        offset query int false "Смещение"
+// @Success      200  {object} dto.AdminUsersResponse "Пользователи"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/admin/users [get]
+func (h *AuthHandler) SearchUsers(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	users, total, err := h.sc.SearchUsers(c.Query("email"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.AdminUserResponse, 0, len(users))
+	for _, u := range users {
+		response = append(response, dto.AdminUserResponse{
+			ID:               u.ID,
+			Email:            u.Email,
+			IsVerified:       u.IsVerified,
+			Role:             u.Role,
+			TOTPEnabled:      u.TOTPEnabled,
+			SuspendedAt:      u.SuspendedAt,
+			SuspendedUntil:   u.SuspendedUntil,
+			SuspensionReason: u.SuspensionReason,
+			ToBeDeletedAt:    u.ToBeDeletedAt,
+			CreatedAt:        u.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, dto.AdminUsersResponse{Users: response, Total: total})
+}
+
+// AdminVerifyUser
+// @Summary      Подтвердить аккаунт
+// @Description  Только для администраторов. Помечает аккаунт подтверждённым без кода из письма.
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Success      200  {object} dto.MessageResponse "Аккаунт подтверждён"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Failure      409  {object} dto.ErrorResponse "Аккаунт уже подтверждён"
+// @Router       /auth/admin/users/{id}/verify [post]
+func (h *AuthHandler) AdminVerifyUser(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.sc.AdminVerifyUser(actorID, targetID, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Аккаунт подтверждён"})
+}
+
+// AdminSuspendUser
+// @Summary      Заблокировать аккаунт
+// @Description  Только для администраторов. До окончания срока (или бессрочно) запрещает вход и обновление токенов, завершает все сессии; user и chat сервисы перестают принимать запросы пользователя и закрывают его WebSocket.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Param        request body dto.SuspendRequest true "Причина и срок"
+// @Success      200  {object} dto.MessageResponse "Аккаунт заблокирован"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID или собственный аккаунт"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Failure      409  {object} dto.ErrorResponse "Аккаунт уже заблокирован"
+// @Router       /auth/admin/users/{id}/suspend [post]
+func (h *AuthHandler) AdminSuspendUser(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	var req dto.SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		return
+	}
+	if err := h.sc.AdminSuspendUser(actorID, targetID, req.Reason, req.Until, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Аккаунт заблокирован"})
+}
+
+// AdminUnsuspendUser
+// @Summary      Разблокировать аккаунт
+// @Description  Только для администраторов. Снимает блокировку досрочно.
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Success      200  {object} dto.MessageResponse "Аккаунт разблокирован"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Failure      409  {object} dto.ErrorResponse "Аккаунт не заблокирован"
+// @Router       /auth/admin/users/{id}/unsuspend [post]
+func (h *AuthHandler) AdminUnsuspendUser(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.sc.AdminUnsuspendUser(actorID, targetID, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Аккаунт разблокирован"})
+}
+
+// AdminForceLogout
+// @Summary      Завершить все сессии пользователя
+// @Description  Только для администраторов. Отзывает все refresh-токены пользователя и выданные из этих сессий access-токены.
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Success      200  {object} dto.MessageResponse "Сессии завершены"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Router       /auth/admin/users/{id}/logout [post]
+func (h *AuthHandler) AdminForceLogout(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.sc.AdminForceLogout(actorID, targetID, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Сессии завершены"})
+}
+
+// AdminScheduleDeletion
+// @Summary      Запланировать удаление аккаунта
+// @Description  Только для администраторов. Аккаунт будет удалён через 3 дня, все сессии завершаются сразу; пользователь может отменить удаление обычным способом.
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Success      200  {object} dto.AdminDeletionResponse "Удаление запланировано"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID или собственный аккаунт"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Failure      409  {object} dto.ErrorResponse "Удаление уже запланировано"
+// @Router       /auth/admin/users/{id}/deletion [post]
+func (h *AuthHandler) AdminScheduleDeletion(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	deletionAt, err := h.sc.AdminScheduleDeletion(actorID, targetID, c.ClientIP())
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AdminDeletionResponse{DeletionAt: deletionAt})
+}
+
+// AdminCancelDeletion
+// @Summary      Отменить удаление аккаунта
+// @Description  Только для администраторов.
+// @Tags         admin
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Success      200  {object} dto.MessageResponse "Удаление отменено"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Failure      409  {object} dto.ErrorResponse "Удаление не запланировано"
+// @Router       /auth/admin/users/{id}/deletion [delete]
+func (h *AuthHandler) AdminCancelDeletion(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.sc.AdminCancelDeletion(actorID, targetID, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Удаление отменено"})
+}
+
+// AdminSetRole
+// @Summary      Изменить роль пользователя
+// @Description  Только для администраторов. Сессии пользователя завершаются, чтобы новая роль сразу попала в токены.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID пользователя"
+// @Param        request body dto.SetRoleRequest true "Новая роль"
+// @Success      200  {object} dto.MessageResponse "Роль изменена"
+// @Failure      400  {object} dto.ErrorResponse "Неверные данные или собственный аккаунт"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      404  {object} dto.ErrorResponse "Пользователь не найден"
+// @Router       /auth/admin/users/{id}/role [put]
+func (h *AuthHandler) AdminSetRole(c *gin.Context) {
+	actorID, targetID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+	var req dto.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		return
+	}
+	if err := h.sc.AdminSetRole(actorID, targetID, req.Role, c.ClientIP()); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Роль изменена"})
+}
+
+// ListAuditEntries
+// @Summary      Журнал действий администраторов
+// @Description  Только для администраторов. Новые записи сверху; можно отфильтровать по пользователю, над которым выполнялись действия.
+// @Tags         admin
+// @Produce      json
+// @Param        target_id query string false "ID пользователя"
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Param        offset query int false "Смещение"
+// @Success      200  {object} dto.AuditEntriesResponse "Записи журнала"
+// @Failure      400  {object} dto.ErrorResponse "Неверный ID"
+// @Failure      403  {object} dto.ErrorResponse "Недостаточно прав"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/admin/audit [get]
+func (h *AuthHandler) ListAuditEntries(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	var targetID *uuid.UUID
+	if raw := c.Query("target_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid target id"})
+			return
+		}
+		targetID = &id
+	}
+
+	entries, total, err := h.sc.ListAuditEntries(targetID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.AuditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		response = append(response, dto.AuditEntryResponse{
+			ID:        e.ID,
+			ActorID:   e.ActorID,
+			TargetID:  e.TargetID,
+			Action:    e.Action,
+			Details:   e.Details,
+			IP:        e.IP,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, dto.AuditEntriesResponse{Entries: response, Total: total})
+}
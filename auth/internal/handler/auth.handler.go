@@ -1,23 +1,33 @@
 package handler
 
 import (
-	"auth/config"
 	"auth/internal/dto"
+	"auth/internal/models"
 	"auth/internal/service"
+	"auth/pkg/jwk"
+	"auth/pkg/mailer"
 	"auth/pkg/rabbitmq"
 	"auth/pkg/utils"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// oauthStateCookie связывает state OAuth с браузером, начавшим вход: без него callback можно подсунуть чужому браузеру
+const oauthStateCookie = "oauth_state"
+
+// trustedDeviceCookie — cookie доверенного устройства, выдаётся после входа с OTP и remember_device
+const trustedDeviceCookie = "trusted_device"
+
 type AuthHandler struct {
 	sc service.AuthService
 }
@@ -26,6 +36,116 @@ func NewAuthHandler(sc service.AuthService) *AuthHandler {
 	return &AuthHandler{sc: sc}
 }
 
+// respondLocked отвечает 429, если err — блокировка после неудачных попыток
+func respondLocked(c *gin.Context, err error) bool {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.JSON(http.StatusTooManyRequests, dto.LockedErrorResponse{
+		Code:        429,
+		Error:       "too many failed attempts",
+		LockedUntil: locked.Until,
+	})
+	return true
+}
+
+// respondSuspended отвечает 403 с причиной и сроком, если err — *service.SuspendedError
+func respondSuspended(c *gin.Context, err error) bool {
+	var suspended *service.SuspendedError
+	if !errors.As(err, &suspended) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, dto.SuspendedErrorResponse{
+		Code:   403,
+		Error:  suspended.Error(),
+		Reason: suspended.Reason,
+		Until:  suspended.Until,
+	})
+	return true
+}
+
+// respondPasswordPolicy отвечает 400 со списком нарушений на языке клиента, если err — *service.PasswordPolicyError
+func respondPasswordPolicy(c *gin.Context, err error, field string) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	lang := mailer.Language(c.GetHeader("Accept-Language"))
+	violations := make([]dto.FieldViolation, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		violations = append(violations, dto.FieldViolation{Field: field, Code: v.Code, Message: v.Message(lang)})
+	}
+	c.JSON(http.StatusBadRequest, dto.PasswordPolicyErrorResponse{
+		Code:       400,
+		Error:      policyErr.Error(),
+		Violations: violations,
+	})
+	return true
+}
+
+// checkSecondFactor проверяет код 2FA или код восстановления; при ошибке сам пишет ответ и возвращает false
+func (h *AuthHandler) checkSecondFactor(c *gin.Context, userID uuid.UUID, totpCode, recoveryCode string) bool {
+	switch {
+	case totpCode != "":
+		if err := h.sc.VerifyTOTP(userID, totpCode, c.ClientIP()); err != nil {
+			if !respondLocked(c, err) {
+				c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid 2FA code"})
+			}
+			return false
+		}
+	case recoveryCode != "":
+		if err := h.sc.UseRecoveryCode(userID, recoveryCode, c.ClientIP()); err != nil {
+			if !respondLocked(c, err) {
+				c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid recovery code"})
+			}
+			return false
+		}
+	default:
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "2FA code required"})
+		return false
+	}
+	return true
+}
+
+// recordEvent пишет событие в журнал безопасности с IP и устройством текущего запроса
+func (h *AuthHandler) recordEvent(c *gin.Context, userID uuid.UUID, eventType, details string) {
+	h.sc.RecordSecurityEvent(userID, eventType, c.ClientIP(), c.Request.UserAgent(), details)
+}
+
+// completeLogin завершает вход без email-OTP: выдаёт токены или, если аккаунт ожидает удаления, токен восстановления
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	if user.ToBeDeletedAt != nil {
+		recoveryToken, err := utils.GenerateTempToken(user.ID, 15*time.Minute, "recovery_token")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate recovery token"})
+			return
+		}
+		c.JSON(http.StatusOK, dto.RecoveryResponse{
+			Message:       "Восстановление аккаунта",
+			RecoveryToken: recoveryToken,
+		})
+		return
+	}
+
+	userAgent := c.Request.UserAgent()
+	access, refresh, err := h.sc.GenerateTokens(user.ID, c.ClientIP(), userAgent, utils.ParseDeviceInfo(userAgent))
+	if err != nil {
+		if respondSuspended(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate tokens"})
+		return
+	}
+	c.SetCookie("access_token", access, 15*60, "/", "", false, true)
+	c.SetCookie("refresh_token", refresh, 30*24*60*60, "/", "", false, true)
+	h.sc.RecordLogin(user.ID, c.ClientIP(), userAgent, mailer.Language(c.GetHeader("Accept-Language")))
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "Успешная авторизация",
+	})
+}
+
 // Register
 // @Summary Регистрация нового пользователя
 // @Description Создаёт нового пользователя с указанным email и паролем. После успешной регистрации отправляется OTP-код
@@ -34,7 +154,7 @@ func NewAuthHandler(sc service.AuthService) *AuthHandler {
 // @Produce json
 // @Param body body dto.AuthRequest true "Данные для регистрации"
 // @Success 200  {object} dto.OTPSentResponse "Подтвердите регистрацию"
-// @Failure      400  {object} dto.ErrorResponse "Некорректные входные данные"
+// @Failure      400  {object} dto.PasswordPolicyErrorResponse "Некорректные входные данные или пароль не соответствует требованиям"
 // @Failure      409  {object} dto.ErrorResponse "Пользователь с таким email уже существует"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
 // @Router       /auth/register [post]
@@ -48,6 +168,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Создаём пользователя
 	id, err := h.sc.Register(input.Email, input.Password)
 	if err != nil {
+		if respondPasswordPolicy(c, err, "password") {
+			return
+		}
 		if err.Error() == "email already exists" {
 			c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
 		} else {
@@ -57,7 +180,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Отправляем OTP
-	_, _, err = h.sc.SendOTP(id, input.Email)
+	_, _, err = h.sc.SendOTP(id, input.Email, mailer.Language(c.GetHeader("Accept-Language")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate OTP"})
 		return
@@ -72,14 +195,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // Login
 // @Summary      Вход в систему
 // @Description  Аутентифицирует пользователя по email и паролю. При успехе отправляется OTP-код.
+// @Description  Если у пользователя включена 2FA, в ответе two_factor = true и при подтверждении нужно передать totp_code.
+// @Description  С доверенного устройства (cookie trusted_device) и без 2FA OTP не отправляется: токены выдаются сразу.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        body body dto.AuthRequest true "Данные для входа"
 // @Success      200  {object} dto.OTPSentResponse "Подтвердите вход"
+// @Success      200  {object} dto.MessageResponse "Вход с доверенного устройства"
 // @Failure      400  {object} dto.ErrorResponse "Некорректные входные данные"
 // @Failure      401  {object} dto.ErrorResponse "Неверный email или пароль"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      403  {object} dto.SuspendedErrorResponse "Аккаунт заблокирован"
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var input dto.AuthRequest
@@ -88,36 +216,63 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	id, err := h.sc.Login(input.Email, input.Password)
+	id, err := h.sc.Login(input.Email, input.Password, c.ClientIP())
 	if err != nil {
+		if user, findErr := h.sc.GetUserByEmail(input.Email); findErr == nil {
+			h.recordEvent(c, user.ID, models.EventLoginFailed, "")
+		}
+		if respondLocked(c, err) {
+			return
+		}
+		if respondSuspended(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid username or password"})
 		return
 	}
+	user, err := h.sc.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	// Доверенное устройство заменяет только email-OTP: код 2FA проверяется на шаге /auth/verify
+	if !user.TOTPEnabled {
+		if token, err := c.Cookie(trustedDeviceCookie); err == nil && h.sc.IsTrustedDevice(user.ID, token, c.ClientIP()) {
+			h.completeLogin(c, user)
+			return
+		}
+	}
 
 	// Отправляем OTP
-	_, _, err = h.sc.SendOTP(id, input.Email)
+	_, _, err = h.sc.SendOTP(id, input.Email, mailer.Language(c.GetHeader("Accept-Language")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate OTP"})
 		return
 	}
 
 	c.JSON(http.StatusOK, dto.OTPSentResponse{
-		UserID:  id,
-		Message: "OTP-код отправлен на указанную почту",
+		UserID:    id,
+		Message:   "OTP-код отправлен на указанную почту",
+		TwoFactor: user.TOTPEnabled,
 	})
 }
 
 // VerifyOTP
 // @Summary      Подтверждение OTP-кода
 // @Description  Проверяет введённый пользователем OTP-код. При успехе выдаёт access и refresh токены в cookie.
+// @Description  Для action = "reset" вместо cookie возвращает одноразовый токен сброса пароля (dto.TempTokenResponse).
+// @Description  При входе с remember_device = true дополнительно выдаётся cookie trusted_device: следующие входы с этого браузера без OTP.
 // @Tags         otp
 // @Accept       json
 // @Produce      json
 // @Param        body body dto.VerifyOTPRequest true "Данные для верификации"
 // @Success      200  {object} dto.MessageResponse "Успешная аутентификация"
 // @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
-// @Failure      401  {object} dto.ErrorResponse "Неверный/просроченный код или временный токен"
+// @Failure      401  {object} dto.ErrorResponse "Неверный/просроченный код, временный токен или код 2FA"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      403  {object} dto.SuspendedErrorResponse "Аккаунт заблокирован"
 // @Router       /auth/verify [post]
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	var req dto.VerifyOTPRequest
@@ -126,21 +281,29 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	otp, err := h.sc.FindValidOTP(req.UserID, req.Code)
+	otp, err := h.sc.FindValidOTP(req.UserID, req.Code, c.ClientIP())
 	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid or expired OTP"})
 		return
 	}
 
-	// Помечаем как использованный
-	err = h.sc.MarkOTPAsUsed(otp.ID)
-
 	user, err := h.sc.GetUserByID(req.UserID)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid user"})
 		return
 	}
 
+	// Второй фактор проверяется до того, как OTP будет израсходован
+	if req.Action == "login" && user.TOTPEnabled && !h.checkSecondFactor(c, user.ID, req.TOTPCode, req.RecoveryCode) {
+		return
+	}
+
+	// Помечаем как использованный
+	err = h.sc.MarkOTPAsUsed(otp.ID)
+
 	ip := c.ClientIP()
 	userAgent := c.Request.UserAgent()
 	device := utils.ParseDeviceInfo(userAgent)
@@ -158,9 +321,25 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 			})
 			return
 		}
-		access, refresh, _ := h.sc.GenerateTokens(req.UserID, ip, userAgent, device)
+		access, refresh, err := h.sc.GenerateTokens(req.UserID, ip, userAgent, device)
+		if err != nil {
+			if !respondSuspended(c, err) {
+				c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate tokens"})
+			}
+			return
+		}
 		c.SetCookie("access_token", access, 15*60, "/", "", false, true)
 		c.SetCookie("refresh_token", refresh, 30*24*60*60, "/", "", false, true)
+		h.sc.RecordLogin(user.ID, ip, userAgent, mailer.Language(c.GetHeader("Accept-Language")))
+
+		if req.RememberDevice {
+			token, expiresAt, err := h.sc.TrustDevice(user.ID, ip, userAgent)
+			if err != nil {
+				log.Printf("[Trusted] Failed to trust device for %s: %v", user.ID, err)
+			} else {
+				c.SetCookie(trustedDeviceCookie, token, int(time.Until(expiresAt).Seconds()), "/", "", false, true)
+			}
+		}
 
 		c.JSON(http.StatusOK, dto.MessageResponse{
 			Message: "Успешная авторизация",
@@ -193,6 +372,17 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 			Message: "Успешная регистрация",
 		})
 		return
+	} else if req.Action == "reset" {
+		resetToken, err := utils.GenerateTempToken(user.ID, 10*time.Minute, "reset_token")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate reset token"})
+			return
+		}
+		c.JSON(http.StatusOK, dto.TempTokenResponse{
+			UserID:    user.ID,
+			TempToken: resetToken,
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, dto.MessageResponse{
@@ -208,6 +398,7 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 // @Success      200  {object} dto.MessageResponse "Токены успешно обновлены"
 // @Failure      401  {object} dto.ErrorResponse "Отсутствует или недействителен refresh-токен"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка"
+// @Failure      403  {object} dto.SuspendedErrorResponse "Аккаунт заблокирован"
 // @Router       /auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	refreshToken, err := c.Cookie("refresh_token")
@@ -217,8 +408,11 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	access, refresh, err := h.sc.Refresh(refreshToken)
+	access, refresh, err := h.sc.Refresh(refreshToken, c.ClientIP())
 	if err != nil {
+		if respondSuspended(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
 		return
 	}
@@ -231,22 +425,32 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	})
 }
 
+// ResendOTP
+// @Summary      Повторная отправка OTP-кода
+// @Description  Отправляет новый код на почту, указанную в аккаунте. Не больше 3 писем в 10 минут на пользователя.
+// @Tags         otp
+// @Produce      json
+// @Param        id query string true "ID пользователя из ответа login/register"
+// @Success      200  {object} dto.MessageResponse "Код отправлен"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
+// @Failure      429  {object} dto.ErrorResponse "Слишком много запросов"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/resend [post]
 func (h *AuthHandler) ResendOTP(c *gin.Context) {
-	id := c.DefaultQuery("id", "")
-	email := c.DefaultQuery("email", "")
-
-	if id == "" || email == "" {
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Требуются ID и Email"})
+	userID, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Некорректный UUID"})
 		return
 	}
 
-	userID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Некорректный UUID"})
+	// Адрес берётся только из аккаунта: иначе код можно было бы получить на чужую почту
+	user, err := h.sc.GetUserByID(userID)
+	if err != nil || user.Type == models.UserTypeBot {
+		c.JSON(http.StatusOK, dto.MessageResponse{Message: "OTP-код сгенерирован"})
 		return
 	}
 
-	_, _, err = h.sc.SendOTP(userID, email)
+	_, _, err = h.sc.SendOTP(user.ID, user.Email, mailer.Language(c.GetHeader("Accept-Language")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "Ошибка генерации OTP-кода"})
 		return
@@ -254,6 +458,88 @@ func (h *AuthHandler) ResendOTP(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.MessageResponse{Message: "OTP-код сгенерирован"})
 }
 
+// JWKS
+// @Summary      Публичные ключи подписи токенов
+// @Description  JWK Set для проверки access-токенов другими сервисами. Содержит все ключи, выданные токены которых ещё могут быть действительны.
+// @Tags         token
+// @Produce      json
+// @Success      200  {object} jwk.KeySet "Набор публичных ключей"
+// @Router       /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwk.PublicKeySet())
+}
+
+// ! Вход по ссылке (без пароля)
+
+// RequestMagicLink
+// @Summary      Запрос ссылки для входа
+// @Description  Отправляет на почту одноразовую ссылку для входа без пароля. Ответ одинаковый, даже если аккаунта нет.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.MagicLinkRequest true "Email"
+// @Success      200  {object} dto.MessageResponse "Ссылка отправлена"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/magic-link [post]
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req dto.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	if err := h.sc.SendMagicLink(req.Email, mailer.Language(c.GetHeader("Accept-Language"))); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to send magic link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Если аккаунт существует, ссылка для входа отправлена на почту"})
+}
+
+// VerifyMagicLink
+// @Summary      Вход по ссылке
+// @Description  Обменивает одноразовый токен из ссылки на access и refresh токены в cookie. При включённой 2FA нужен totp_code или recovery_code.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.MagicLinkVerifyRequest true "Токен из ссылки"
+// @Success      200  {object} dto.MessageResponse "Успешная авторизация"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Недействительная ссылка или код 2FA"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/magic-link/verify [post]
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	var req dto.MagicLinkVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	// Токен расходуется только после проверки 2FA, чтобы ошибка в коде не сжигала ссылку
+	userID, err := h.sc.PeekTempToken(req.Token, "magic_link")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid or expired link"})
+		return
+	}
+	user, err := h.sc.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid user"})
+		return
+	}
+	if user.TOTPEnabled && !h.checkSecondFactor(c, user.ID, req.TOTPCode, req.RecoveryCode) {
+		return
+	}
+	if _, err := h.sc.ConsumeTempToken(req.Token, "magic_link"); err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid or expired link"})
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
 // ! Выход из профиля
 
 // LogoutCurrent
@@ -307,6 +593,7 @@ func (h *AuthHandler) LogoutAll(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "Logout all failed"})
 		return
 	}
+	h.recordEvent(c, userID, models.EventSessionRevoked, "all")
 
 	// Добавляем текущий access-токен в blacklist
 	accessToken, _ := c.Cookie("access_token")
@@ -354,7 +641,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	// Отправляем OTP
-	_, _, err = h.sc.SendOTP(user.ID, email)
+	_, _, err = h.sc.SendOTP(user.ID, email, mailer.Language(c.GetHeader("Accept-Language")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate OTP"})
 		return
@@ -368,14 +655,14 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 
 // ResetPassword
 // @Summary      Сброс пароля по OTP-токену
-// @Description  Меняет пароль пользователя после успешной проверки OTP и временного токена.
+// @Description  Меняет пароль пользователя по одноразовому reset_token, который выдаёт /auth/verify с action = "reset".
 // @Description  После успешного сброса автоматически выходит со всех устройств (отзывает все refresh-токены и добавляет текущий access в blacklist).
 // @Tags         reset
 // @Accept       json
 // @Produce      json
 // @Param        body body dto.ResetPasswordRequest true "Токен и новый пароль"
 // @Success      200  {object} dto.MessageResponse "Пароль успешно изменён, выполнен выход со всех устройств"
-// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      400  {object} dto.PasswordPolicyErrorResponse "Некорректные данные или пароль не соответствует требованиям"
 // @Failure      401  {object} dto.ErrorResponse "Недействительный или просроченный токен"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
 // @Router       /auth/reset-password [post]
@@ -386,15 +673,39 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// Проверяем пароль заранее, чтобы не сжечь одноразовый токен на невалидном запросе
+	peekID, err := h.sc.PeekTempToken(req.ResetToken, "reset_token")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		return
+	}
+	if err := h.sc.ValidateNewPassword(peekID, req.NewPassword); err != nil {
+		if !respondPasswordPolicy(c, err, "new_password") {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
+		return
+	}
+
+	// Токен сброса одноразовый и выдаётся только после подтверждения OTP (action = "reset")
+	userID, err := h.sc.ConsumeTempToken(req.ResetToken, "reset_token")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		return
+	}
+
 	// Меняем пароль
-	err := h.sc.UpdatePassword(req.UserID, req.NewPassword)
+	err = h.sc.UpdatePassword(userID, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		if !respondPasswordPolicy(c, err, "new_password") {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
 		return
 	}
 
+	h.recordEvent(c, userID, models.EventPasswordChanged, "reset")
+
 	// Выходим со всех устройств
-	_ = h.sc.RevokeAllRefreshTokens(req.UserID)
+	_ = h.sc.RevokeAllRefreshTokens(userID)
 	accessToken, _ := c.Cookie("access_token")
 	if accessToken != "" {
 		_ = h.sc.BlacklistAccessToken(c, accessToken)
@@ -405,6 +716,130 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Password reset successful"})
 }
 
+// ChangePassword
+// @Summary      Смена пароля
+// @Description  Меняет пароль авторизованного пользователя после проверки текущего.
+// @Description  При revoke_other_sessions = true завершает все сессии, кроме текущей.
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ChangePasswordRequest true "Текущий и новый пароль"
+// @Success      200  {object} dto.MessageResponse "Пароль изменён"
+// @Failure      400  {object} dto.PasswordPolicyErrorResponse "Некорректные данные или пароль не соответствует требованиям"
+// @Failure      401  {object} dto.ErrorResponse "Неверный текущий пароль"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	if err := h.sc.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if respondPasswordPolicy(c, err, "new_password") {
+			return
+		}
+		if err.Error() == "invalid current password" {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
+		return
+	}
+	h.recordEvent(c, userID, models.EventPasswordChanged, "")
+
+	if req.RevokeOtherSessions {
+		refreshToken, _ := c.Cookie("refresh_token")
+		if err := h.sc.RevokeOtherRefreshTokens(userID, refreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "Password changed, but failed to revoke other sessions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Пароль успешно изменён"})
+}
+
+// ChangeEmail
+// @Summary      Запрос на смену email
+// @Description  Проверяет пароль и свободность нового адреса, отправляет OTP-коды на текущую и новую почту
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ChangeEmailRequest true "Новый email и текущий пароль"
+// @Success      200  {object} dto.MessageResponse "Коды отправлены"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неверный пароль"
+// @Failure      409  {object} dto.ErrorResponse "Email уже занят"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/email [post]
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	err := h.sc.RequestEmailChange(userID, req.NewEmail, req.Password, mailer.Language(c.GetHeader("Accept-Language")))
+	if err != nil {
+		switch err.Error() {
+		case "invalid password":
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		case "email already exists":
+			c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
+		case "new email matches the current one":
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "OTP-коды отправлены на текущую и новую почту"})
+}
+
+// ConfirmEmailChange
+// @Summary      Подтверждение смены email
+// @Description  Меняет email только если подтверждены оба кода: с текущей и с новой почты
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ConfirmEmailChangeRequest true "Коды с обеих почт"
+// @Success      200  {object} dto.MessageResponse "Email изменён"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неверные или просроченные коды"
+// @Failure      409  {object} dto.ErrorResponse "Email уже занят"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
+// @Router       /auth/email/confirm [post]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	email, err := h.sc.ConfirmEmailChange(userID, req.OldCode, req.NewCode, c.ClientIP())
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "email already exists":
+			c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
+		case "invalid or expired OTP", "no pending email change":
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Email изменён на " + email})
+}
+
 // ! Информация для пользователя
 
 // Me
@@ -435,7 +870,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 
 // ListSessions
 // @Summary      Получить список активных сессий
-// @Description  Возвращает список всех устройств/браузеров, с которых пользователь сейчас залогинен (активные refresh-токены)
+// @Description  Возвращает список всех устройств/браузеров, с которых пользователь сейчас залогинен. ID сессии не меняется при обновлении токенов, текущая сессия помечена current.
 // @Tags         user
 // @Produce      json
 // @Success      200  {array} dto.SessionResponse "Список сессий"
@@ -444,6 +879,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 // @Router       /auth/sessions [get]
 func (h *AuthHandler) ListSessions(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
+	currentID, _ := c.Get("sessionID") // из access-токена в cookie
 
 	sessions, err := h.sc.ListActiveSessions(userID)
 	if err != nil {
@@ -451,57 +887,224 @@ func (h *AuthHandler) ListSessions(c *gin.Context) {
 		return
 	}
 
-	var response []dto.SessionResponse
+	response := make([]dto.SessionResponse, 0, len(sessions))
 	for _, s := range sessions {
 		response = append(response, dto.SessionResponse{
-			ID:        s.ID,
-			Device:    s.Device,
-			IP:        s.IP,
-			UserAgent: s.UserAgent,
-			CreatedAt: s.ExpiresAt.Add(-config.Env.RefreshTokenDuration),
-			ExpiresAt: s.ExpiresAt,
+			ID:         s.FamilyID,
+			Device:     s.Device,
+			IP:         s.IP,
+			LastIP:     s.LastIP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.SessionCreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    currentID == s.FamilyID,
 		})
 	}
 	c.JSON(http.StatusOK, response)
 }
 
-// ! Удаление аккаунта
-
-// Delete
-// @Summary      Запрос на удаление аккаунта
-// @Description  Повторный ввод пароля, генерация токена удаления
+// RevokeSession
+// @Summary      Завершить сессию
+// @Description  Отзывает refresh-токен выбранной сессии; access-токены, выданные из неё, перестают приниматься
 // @Tags         user
 // @Produce      json
-// @Success      200  {object} dto.TempTokenResponse "Токен для удаления"
-// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Param        id path string true "ID сессии из /auth/sessions"
+// @Success      200  {object} dto.MessageResponse "Сессия завершена"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
 // @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Сессия не найдена"
 // @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
-// @Router       /auth/delete [post]
-func (h *AuthHandler) Delete(c *gin.Context) {
+// @Router       /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
-	var req dto.DeleteAccountRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "В теле запроса переданы некорректные данные"})
-		return
-	}
-
-	// Подтверждение пароля
-	user, err := h.sc.GetUserByID(userID)
-	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
-		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "Invalid password"})
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid session id"})
 		return
 	}
 
-	// Генерируем temp-токен для подтверждения удаления
-	deleteToken, err := utils.GenerateTempToken(user.ID, 15*time.Minute, "delete_token")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate delete token"})
+	if err := h.sc.RevokeSession(userID, sessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "session not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, dto.TempTokenResponse{
-		UserID:    userID,
-		TempToken: deleteToken,
-	})
+	h.recordEvent(c, userID, models.EventSessionRevoked, sessionID.String())
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Сессия завершена"})
+}
+
+// ListTrustedDevices
+// @Summary      Доверенные устройства
+// @Description  Возвращает браузеры, с которых вход выполняется без email-OTP; текущий помечен current
+// @Tags         user
+// @Produce      json
+// @Success      200  {array} dto.TrustedDeviceResponse "Список устройств"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/trusted-devices [get]
+func (h *AuthHandler) ListTrustedDevices(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var currentID uuid.UUID
+	if token, err := c.Cookie(trustedDeviceCookie); err == nil {
+		currentID, _ = h.sc.TrustedDeviceID(userID, token)
+	}
+
+	devices, err := h.sc.ListTrustedDevices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.TrustedDeviceResponse, 0, len(devices))
+	for _, d := range devices {
+		response = append(response, dto.TrustedDeviceResponse{
+			ID:         d.ID,
+			Device:     d.Device,
+			IP:         d.IP,
+			LastIP:     d.LastIP,
+			UserAgent:  d.UserAgent,
+			CreatedAt:  d.CreatedAt,
+			LastUsedAt: d.LastUsedAt,
+			ExpiresAt:  d.ExpiresAt,
+			Current:    currentID == d.ID,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeTrustedDevice
+// @Summary      Отозвать доверенное устройство
+// @Description  Следующий вход с этого браузера снова потребует email-OTP
+// @Tags         user
+// @Produce      json
+// @Param        id path string true "ID устройства из /auth/trusted-devices"
+// @Success      200  {object} dto.MessageResponse "Устройство отозвано"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Устройство не найдено"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/trusted-devices/{id} [delete]
+func (h *AuthHandler) RevokeTrustedDevice(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid device id"})
+		return
+	}
+
+	if err := h.sc.RevokeTrustedDevice(userID, deviceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "device not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	h.recordEvent(c, userID, models.EventTrustedDeviceRevoked, deviceID.String())
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Устройство отозвано"})
+}
+
+// RevokeAllTrustedDevices
+// @Summary      Отозвать все доверенные устройства
+// @Description  Все следующие входы снова потребуют email-OTP
+// @Tags         user
+// @Produce      json
+// @Success      200  {object} dto.MessageResponse "Устройства отозваны"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/trusted-devices [delete]
+func (h *AuthHandler) RevokeAllTrustedDevices(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	if err := h.sc.RevokeAllTrustedDevices(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	c.SetCookie(trustedDeviceCookie, "", -1, "/", "", false, true)
+	h.recordEvent(c, userID, models.EventTrustedDeviceRevoked, "all")
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Устройства отозваны"})
+}
+
+// ListSecurityEvents
+// @Summary      Журнал безопасности
+// @Description  Возвращает события безопасности пользователя (входы, неудачные попытки, смена пароля, завершение сессий, удаление аккаунта), новые сверху
+// @Tags         user
+// @Produce      json
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Param        offset query int false "Смещение"
+// @Success      200  {object} dto.SecurityEventsResponse "События"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/security-events [get]
+func (h *AuthHandler) ListSecurityEvents(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	limit, offset := pagination(c)
+
+	events, total, err := h.sc.ListSecurityEvents(userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.SecurityEventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, dto.SecurityEventResponse{
+			ID:        e.ID,
+			Type:      e.Type,
+			IP:        e.IP,
+			Device:    e.Device,
+			UserAgent: e.UserAgent,
+			Details:   e.Details,
+			NewDevice: e.NewDevice,
+			Count:     e.Count,
+			LastSeen:  e.LastSeenAt,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, dto.SecurityEventsResponse{Events: response, Total: total})
+}
+
+// ! Удаление аккаунта
+
+// Delete
+// @Summary      Запрос на удаление аккаунта
+// @Description  Повторный ввод пароля, генерация токена удаления
+// @Tags         user
+// @Produce      json
+// @Success      200  {object} dto.TempTokenResponse "Токен для удаления"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/delete [post]
+func (h *AuthHandler) Delete(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "В теле запроса переданы некорректные данные"})
+		return
+	}
+
+	// Подтверждение пароля
+	if err := h.sc.CheckPassword(userID, req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "Invalid password"})
+		return
+	}
+
+	// Генерируем temp-токен для подтверждения удаления
+	deleteToken, err := utils.GenerateTempToken(userID, 15*time.Minute, "delete_token")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate delete token"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.TempTokenResponse{
+		UserID:    userID,
+		TempToken: deleteToken,
+	})
 }
 
 // DeleteConfirm
@@ -536,6 +1139,7 @@ func (h *AuthHandler) DeleteConfirm(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "Failed to schedule deletion"})
 		return
 	}
+	h.recordEvent(c, userUUID, models.EventDeletionScheduled, "")
 
 	// Выходим со всех устройств
 	_ = h.sc.RevokeAllRefreshTokens(userUUID)
@@ -581,6 +1185,856 @@ func (h *AuthHandler) DeleteCancel(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
 		return
 	}
+	h.recordEvent(c, userUUID, models.EventDeletionCancelled, "")
 
 	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Аккаунт успешно восстановлен"})
 }
+
+// ! Двухфакторная аутентификация (TOTP)
+
+// EnrollTOTP
+// @Summary      Подключение 2FA
+// @Description  Генерирует секрет TOTP, otpauth:// URI и QR-код для приложения-аутентификатора. 2FA включается только после подтверждения кодом.
+// @Tags         2fa
+// @Produce      json
+// @Success      200  {object} dto.TOTPEnrollResponse "Данные для приложения-аутентификатора"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      409  {object} dto.ErrorResponse "2FA уже включена"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/2fa/enroll [post]
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	key, err := h.sc.EnrollTOTP(userID)
+	if err != nil {
+		if err.Error() == "2FA is already enabled" {
+			c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+
+	qr, err := utils.TOTPQRCode(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate QR code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TOTPEnrollResponse{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: qr,
+	})
+}
+
+// ConfirmTOTP
+// @Summary      Подтверждение подключения 2FA
+// @Description  Проверяет код из приложения-аутентификатора, включает 2FA и выдаёт одноразовые коды восстановления
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.TOTPCodeRequest true "Код из приложения"
+// @Success      200  {object} dto.RecoveryCodesResponse "2FA включена"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неверный код"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
+// @Router       /auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	codes, err := h.sc.ConfirmTOTP(userID, req.Code, c.ClientIP())
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if err.Error() == "invalid 2FA code" {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		Message: "Двухфакторная аутентификация включена. Сохраните коды восстановления, они показываются один раз",
+		Codes:   codes,
+	})
+}
+
+// DisableTOTP
+// @Summary      Отключение 2FA
+// @Description  Отключает 2FA после проверки текущего кода из приложения-аутентификатора
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.TOTPCodeRequest true "Код из приложения"
+// @Success      200  {object} dto.MessageResponse "2FA отключена"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неверный код"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
+// @Router       /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	if err := h.sc.DisableTOTP(userID, req.Code, c.ClientIP()); err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if err.Error() == "invalid 2FA code" {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Двухфакторная аутентификация отключена"})
+}
+
+// RegenerateRecoveryCodes
+// @Summary      Новые коды восстановления
+// @Description  Генерирует новый набор одноразовых кодов восстановления, прежние коды становятся недействительными
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.TOTPCodeRequest true "Код из приложения"
+// @Success      200  {object} dto.RecoveryCodesResponse "Новые коды восстановления"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неверный код"
+// @Router       /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	codes, err := h.sc.RegenerateRecoveryCodes(userID, req.Code, c.ClientIP())
+	if err != nil {
+		if err.Error() == "invalid 2FA code" {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		Message: "Прежние коды восстановления больше не действуют",
+		Codes:   codes,
+	})
+}
+
+// ! Passkeys (WebAuthn)
+
+// BeginPasskeyRegistration
+// @Summary      Начало регистрации passkey
+// @Description  Возвращает параметры для navigator.credentials.create(). Состояние церемонии хранится 5 минут.
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {object} protocol.CredentialCreation "Параметры создания ключа"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys/register/begin [post]
+func (h *AuthHandler) BeginPasskeyRegistration(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	creation, err := h.sc.BeginPasskeyRegistration(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishPasskeyRegistration
+// @Summary      Завершение регистрации passkey
+// @Description  Принимает ответ navigator.credentials.create() как есть и сохраняет ключ
+// @Tags         passkeys
+// @Accept       json
+// @Produce      json
+// @Param        name query string false "Название ключа"
+// @Success      200  {object} dto.PasskeyResponse "Ключ добавлен"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ответ authenticator'а"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys/register/finish [post]
+func (h *AuthHandler) FinishPasskeyRegistration(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	body, err := c.GetRawData()
+	if err != nil || len(c.Query("name")) > 100 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	pk, err := h.sc.FinishPasskeyRegistration(userID, c.Query("name"), body)
+	if err != nil {
+		switch err.Error() {
+		case "invalid passkey response", "passkey ceremony expired or not started":
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, toPasskeyResponse(pk))
+}
+
+// ListPasskeys
+// @Summary      Список passkey
+// @Description  Возвращает ключи WebAuthn текущего пользователя
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {array} dto.PasskeyResponse "Список ключей"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys [get]
+func (h *AuthHandler) ListPasskeys(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	passkeys, err := h.sc.ListPasskeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.PasskeyResponse, 0, len(passkeys))
+	for i := range passkeys {
+		response = append(response, toPasskeyResponse(&passkeys[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeletePasskey
+// @Summary      Удаление passkey
+// @Description  Удаляет ключ WebAuthn текущего пользователя
+// @Tags         passkeys
+// @Produce      json
+// @Param        id path string true "ID ключа"
+// @Success      200  {object} dto.MessageResponse "Ключ удалён"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Ключ не найден"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys/{id} [delete]
+func (h *AuthHandler) DeletePasskey(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	passkeyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid passkey id"})
+		return
+	}
+
+	if err := h.sc.DeletePasskey(userID, passkeyID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "passkey not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Ключ удалён"})
+}
+
+// BeginPasskeyLogin
+// @Summary      Начало входа по passkey
+// @Description  Возвращает параметры для navigator.credentials.get() и session_id церемонии. Email не нужен — ключ обнаруживаемый.
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {object} dto.PasskeyLoginOptionsResponse "Параметры входа"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys/login/begin [post]
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	assertion, sessionID, err := h.sc.BeginPasskeyLogin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.PasskeyLoginOptionsResponse{SessionID: sessionID, Options: assertion})
+}
+
+// FinishPasskeyLogin
+// @Summary      Завершение входа по passkey
+// @Description  Принимает ответ navigator.credentials.get(). При успехе выдаёт токены без email-OTP: ключ с проверкой пользователя сам по себе двухфакторный.
+// @Tags         passkeys
+// @Accept       json
+// @Produce      json
+// @Param        session_id query string true "ID церемонии из /auth/passkeys/login/begin"
+// @Success      200  {object} dto.MessageResponse "Успешная авторизация"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Ключ не прошёл проверку"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/passkeys/login/finish [post]
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	sessionID := c.Query("session_id")
+	body, err := c.GetRawData()
+	if err != nil || sessionID == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	user, err := h.sc.FinishPasskeyLogin(sessionID, body)
+	if err != nil {
+		switch err.Error() {
+		case "invalid passkey response", "passkey ceremony expired or not started":
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+func toPasskeyResponse(pk *models.Passkey) dto.PasskeyResponse {
+	transports := []string{}
+	if pk.Transports != "" {
+		transports = strings.Split(pk.Transports, ",")
+	}
+	return dto.PasskeyResponse{
+		ID:             pk.ID,
+		Name:           pk.Name,
+		Transports:     transports,
+		BackupEligible: pk.BackupEligible,
+		CreatedAt:      pk.CreatedAt,
+		LastUsedAt:     pk.LastUsedAt,
+	}
+}
+
+// ! Вход через внешних провайдеров (OAuth2/OIDC)
+
+// OAuthLogin
+// @Summary      Вход через внешнего провайдера
+// @Description  Перенаправляет на страницу авторизации провайдера (authorization code + PKCE)
+// @Tags         oauth
+// @Param        provider path string true "Провайдер, например google"
+// @Success      302  "Редирект на провайдера"
+// @Failure      404  {object} dto.ErrorResponse "Провайдер не настроен"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/{provider}/login [get]
+func (h *AuthHandler) OAuthLogin(c *gin.Context) {
+	h.redirectToProvider(c, uuid.Nil)
+}
+
+// OAuthLink
+// @Summary      Привязка внешнего провайдера
+// @Description  Перенаправляет на провайдера; после callback'а его аккаунт будет привязан к текущему пользователю
+// @Tags         oauth
+// @Param        provider path string true "Провайдер, например google"
+// @Success      302  "Редирект на провайдера"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Провайдер не настроен"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/{provider}/link [get]
+func (h *AuthHandler) OAuthLink(c *gin.Context) {
+	h.redirectToProvider(c, c.MustGet("userID").(uuid.UUID))
+}
+
+func (h *AuthHandler) redirectToProvider(c *gin.Context, linkUserID uuid.UUID) {
+	url, state, err := h.sc.StartOAuth(c.Param("provider"), linkUserID)
+	if err != nil {
+		if err.Error() == "unknown provider" {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	// Lax: cookie уходит при переходе с провайдера обратно (top-level GET), но не в фоновых запросах с чужих сайтов
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, 10*60, "/api/auth/oauth", "", false, true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// OAuthCallback
+// @Summary      Callback провайдера
+// @Description  Обменивает код на данные пользователя; state должен совпадать с cookie oauth_state, выданной при старте. Новые пользователи создаются сразу подтверждёнными. Если включена 2FA, возвращается временный токен для /auth/oauth/2fa.
+// @Tags         oauth
+// @Produce      json
+// @Param        provider path string true "Провайдер"
+// @Param        code query string true "Код авторизации"
+// @Param        state query string true "State из запроса авторизации"
+// @Success      200  {object} dto.MessageResponse "Успешная авторизация или привязка"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные или недействительный state"
+// @Failure      401  {object} dto.ErrorResponse "Провайдер отклонил авторизацию"
+// @Failure      409  {object} dto.ErrorResponse "Аккаунт уже существует или провайдер привязан к другому аккаунту"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/{provider}/callback [get]
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "missing code or state"})
+		return
+	}
+
+	// state должен совпасть с cookie браузера, который начинал вход или привязку
+	cookieState, err := c.Cookie(oauthStateCookie)
+	c.SetCookie(oauthStateCookie, "", -1, "/api/auth/oauth", "", false, true)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid or expired state"})
+		return
+	}
+
+	result, err := h.sc.CompleteOAuth(c.Param("provider"), state, code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid or expired state", "unknown provider":
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		case "failed to authenticate with provider", "email is not verified by provider":
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		case "account with this email already exists", "identity is already linked to another account", "provider is already linked":
+			c.JSON(http.StatusConflict, dto.ErrorResponse{Code: 409, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+
+	if result.Linked {
+		c.JSON(http.StatusOK, dto.MessageResponse{Message: "Аккаунт провайдера привязан"})
+		return
+	}
+
+	user := result.User
+	if result.Created {
+		// сообщаем, что пользователь создан
+		if err := rabbitmq.PublishUserEvent(user.ID, "user_created", nil); err != nil {
+			log.Printf("[OAuth] Failed to publish user_created event: %v", err)
+		}
+	}
+
+	if user.TOTPEnabled {
+		tempToken, err := utils.GenerateTempToken(user.ID, 10*time.Minute, "oauth_2fa")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, dto.TempTokenResponse{UserID: user.ID, TempToken: tempToken})
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// OAuthTwoFactor
+// @Summary      Второй фактор после входа через провайдера
+// @Description  Завершает вход через провайдера для пользователей с включённой 2FA
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.OAuthTwoFactorRequest true "Временный токен и код 2FA"
+// @Success      200  {object} dto.MessageResponse "Успешная авторизация"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Недействительный токен или код 2FA"
+// @Failure      429  {object} dto.LockedErrorResponse "Слишком много неудачных попыток"
+// @Router       /auth/oauth/2fa [post]
+func (h *AuthHandler) OAuthTwoFactor(c *gin.Context) {
+	var req dto.OAuthTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	userID, err := h.sc.PeekTempToken(req.TempToken, "oauth_2fa")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid or expired token"})
+		return
+	}
+	user, err := h.sc.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid user"})
+		return
+	}
+	if !h.checkSecondFactor(c, user.ID, req.TOTPCode, req.RecoveryCode) {
+		return
+	}
+	if _, err := h.sc.ConsumeTempToken(req.TempToken, "oauth_2fa"); err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: "invalid or expired token"})
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// ListIdentities
+// @Summary      Привязанные провайдеры
+// @Description  Возвращает внешние аккаунты, привязанные к текущему пользователю
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {array} dto.IdentityResponse "Список привязок"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/identities [get]
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	identities, err := h.sc.ListIdentities(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+
+	response := make([]dto.IdentityResponse, 0, len(identities))
+	for _, i := range identities {
+		response = append(response, dto.IdentityResponse{
+			ID:        i.ID,
+			Provider:  i.Provider,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UnlinkIdentity
+// @Summary      Отвязка провайдера
+// @Description  Удаляет привязку внешнего аккаунта. Вход по паролю, passkey и magic link остаётся доступен.
+// @Tags         oauth
+// @Produce      json
+// @Param        provider path string true "Провайдер"
+// @Success      200  {object} dto.MessageResponse "Провайдер отвязан"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Провайдер не привязан"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/{provider} [delete]
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	if err := h.sc.UnlinkIdentity(userID, c.Param("provider")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "provider is not linked"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Провайдер отвязан"})
+}
+
+// ! Боты и персональные токены
+
+// CreateBot
+// @Summary      Создание бота
+// @Description  Создаёт пользователя-бота, принадлежащего текущему пользователю. Бот входит только по персональным токенам.
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.CreateBotRequest true "Имя бота"
+// @Success      200  {object} dto.BotResponse "Бот создан"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные или превышен лимит ботов"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/bots [post]
+func (h *AuthHandler) CreateBot(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.CreateBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	bot, err := h.sc.CreateBot(userID, req.Name)
+	if err != nil {
+		switch err.Error() {
+		case "bot limit reached", "bots cannot create bots":
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.BotResponse{ID: bot.ID, CreatedAt: bot.CreatedAt})
+}
+
+// ListBots
+// @Summary      Список ботов
+// @Tags         tokens
+// @Produce      json
+// @Success      200  {array} dto.BotResponse "Боты текущего пользователя"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/bots [get]
+func (h *AuthHandler) ListBots(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	bots, err := h.sc.ListBots(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	response := make([]dto.BotResponse, 0, len(bots))
+	for _, b := range bots {
+		response = append(response, dto.BotResponse{ID: b.ID, CreatedAt: b.CreatedAt})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteBot
+// @Summary      Удаление бота
+// @Description  Удаляет бота вместе с его токенами
+// @Tags         tokens
+// @Produce      json
+// @Param        id path string true "ID бота"
+// @Success      200  {object} dto.MessageResponse "Бот удалён"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Бот не найден"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/bots/{id} [delete]
+func (h *AuthHandler) DeleteBot(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	botID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid bot id"})
+		return
+	}
+
+	if err := h.sc.DeleteBot(userID, botID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "bot not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Бот удалён"})
+}
+
+// CreateAPIToken
+// @Summary      Выпуск персонального токена
+// @Description  Создаёт токен для заголовка Authorization: Bearer. Разрешения: auth:read, user:read, user:write, chat:read, chat:write. Токен показывается один раз.
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.CreateAPITokenRequest true "Параметры токена"
+// @Success      200  {object} dto.CreatedAPITokenResponse "Токен создан"
+// @Failure      400  {object} dto.ErrorResponse "Некорректные данные"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Бот не найден"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/tokens [post]
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	var req dto.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "Incorrect data was transmitted in the body"})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	plain, token, err := h.sc.CreateAPIToken(userID, req.BotID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		switch {
+		case err.Error() == "bot not found":
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: err.Error()})
+		case strings.HasPrefix(err.Error(), "unknown scope"):
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.CreatedAPITokenResponse{
+		APITokenResponse: toAPITokenResponse(token),
+		Token:            plain,
+	})
+}
+
+// ListAPITokens
+// @Summary      Список персональных токенов
+// @Description  Токены, выпущенные текущим пользователем, в том числе для его ботов
+// @Tags         tokens
+// @Produce      json
+// @Success      200  {array} dto.APITokenResponse "Токены"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/tokens [get]
+func (h *AuthHandler) ListAPITokens(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	tokens, err := h.sc.ListAPITokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	response := make([]dto.APITokenResponse, 0, len(tokens))
+	for i := range tokens {
+		response = append(response, toAPITokenResponse(&tokens[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAPIToken
+// @Summary      Отзыв персонального токена
+// @Tags         tokens
+// @Produce      json
+// @Param        id path string true "ID токена"
+// @Success      200  {object} dto.MessageResponse "Токен отозван"
+// @Failure      400  {object} dto.ErrorResponse "Некорректный ID"
+// @Failure      401  {object} dto.ErrorResponse "Неавторизован"
+// @Failure      404  {object} dto.ErrorResponse "Токен не найден"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/tokens/{id} [delete]
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "invalid token id"})
+		return
+	}
+
+	if err := h.sc.RevokeAPIToken(userID, tokenID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: 404, Error: "token not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Токен отозван"})
+}
+
+func toAPITokenResponse(t *models.APIToken) dto.APITokenResponse {
+	scopes := []string{}
+	if t.Scopes != "" {
+		scopes = strings.Split(t.Scopes, ",")
+	}
+	return dto.APITokenResponse{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// ! Внутренние сервисы
+
+// clientCredentials берёт id и секрет сервиса из HTTP Basic или из полей формы
+func clientCredentials(c *gin.Context, formID, formSecret string) (string, string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		return id, secret
+	}
+	return formID, formSecret
+}
+
+// ServiceToken
+// @Summary      Токен сервиса (client credentials)
+// @Description  Выдаёт внутреннему сервису короткоживущий токен по его id и секрету (HTTP Basic или поля client_id/client_secret)
+// @Tags         token
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type formData string true "Только client_credentials"
+// @Param        client_id formData string false "Id сервиса, если не передан в Basic"
+// @Param        client_secret formData string false "Секрет сервиса, если не передан в Basic"
+// @Success      200  {object} dto.ServiceTokenResponse "Токен"
+// @Failure      400  {object} dto.ErrorResponse "Неподдерживаемый grant_type"
+// @Failure      401  {object} dto.ErrorResponse "Неверные данные сервиса"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/oauth/token [post]
+func (h *AuthHandler) ServiceToken(c *gin.Context) {
+	var req dto.ServiceTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		return
+	}
+	if req.GrantType != "client_credentials" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: "unsupported grant type"})
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(c, req.ClientID, req.ClientSecret)
+	client, err := h.sc.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		return
+	}
+
+	token, ttl, err := h.sc.IssueServiceToken(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.ServiceTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scope:       strings.Join(client.Scopes, " "),
+	})
+}
+
+// Introspect
+// @Summary      Проверка токена (introspection)
+// @Description  Для внутренних сервисов: сообщает, действителен ли access-токен, персональный или сервисный токен, чей он, с какими разрешениями и не отозван ли. Сервис авторизуется своим id и секретом и должен иметь разрешение auth:introspect.
+// @Tags         token
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token formData string true "Проверяемый токен"
+// @Param        client_id formData string false "Id сервиса, если не передан в Basic"
+// @Param        client_secret formData string false "Секрет сервиса, если не передан в Basic"
+// @Success      200  {object} dto.IntrospectResponse "Сведения о токене"
+// @Failure      400  {object} dto.ErrorResponse "Нет токена"
+// @Failure      401  {object} dto.ErrorResponse "Неверные данные сервиса"
+// @Failure      403  {object} dto.ErrorResponse "Нет разрешения auth:introspect"
+// @Failure      500  {object} dto.ErrorResponse "Внутренняя ошибка сервера"
+// @Router       /auth/introspect [post]
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	var req dto.IntrospectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Error: err.Error()})
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(c, req.ClientID, req.ClientSecret)
+	client, err := h.sc.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Error: err.Error()})
+		return
+	}
+	allowed := false
+	for _, scope := range client.Scopes {
+		if scope == service.ScopeIntrospect {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{Code: 403, Error: "client lacks scope " + service.ScopeIntrospect})
+		return
+	}
+
+	result, err := h.sc.Introspect(req.Token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Error: err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.IntrospectResponse{
+		Active:    result.Active,
+		Subject:   result.Subject,
+		Scope:     strings.Join(result.Scopes, " "),
+		SessionID: result.SessionID,
+		Role:      result.Role,
+		TokenType: result.TokenType,
+		ClientID:  result.ClientID,
+		ExpiresAt: result.ExpiresAt,
+		Revoked:   result.Revoked,
+	})
+}
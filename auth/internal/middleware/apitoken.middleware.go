@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"auth/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// authenticateAPIToken проверяет персональный токен той же introspection, что используют user и chat,
+// и разрешение <service>:read/write; при ошибке сам прерывает запрос и возвращает false
+func authenticateAPIToken(c *gin.Context, sc service.AuthService, token, scopePrefix string) bool {
+	info, err := sc.Introspect(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+		return false
+	}
+	userID, err := uuid.Parse(info.Subject)
+	if !info.Active || info.TokenType != service.TokenTypeAPIToken || err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		return false
+	}
+
+	scope := scopePrefix + ":write"
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		scope = scopePrefix + ":read"
+	}
+	allowed := false
+	for _, s := range info.Scopes {
+		if s == scope {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token lacks scope " + scope})
+		return false
+	}
+
+	c.Set("userID", userID)
+	return true
+}
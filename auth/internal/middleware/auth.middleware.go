@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"auth/internal/models"
+	"auth/internal/service"
 	"auth/pkg/redis"
 	"auth/pkg/utils"
 	"net/http"
@@ -10,10 +12,30 @@ import (
 	"github.com/google/uuid"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+var apiTokenRoutes = map[string]bool{
+	"/api/auth/me":              true,
+	"/api/auth/security-events": true,
+}
+
+// AuthMiddleware принимает access-токен из cookie или заголовка Authorization: Bearer, а также персональные токены (lcp_...)
+func AuthMiddleware(sc service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		accessToken, err := c.Cookie("access_token")
-		if err != nil || accessToken == "" {
+		accessToken := bearerToken(c)
+		if utils.IsAPIToken(accessToken) {
+			// Персональные токены в auth-сервисе допускаются только на чтение профиля и журнала
+			if !apiTokenRoutes[c.FullPath()] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API tokens are not allowed for this endpoint"})
+				return
+			}
+			if authenticateAPIToken(c, sc, accessToken, "auth") {
+				c.Next()
+			}
+			return
+		}
+		if accessToken == "" {
+			accessToken, _ = c.Cookie("access_token")
+		}
+		if accessToken == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
@@ -24,6 +46,10 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		claims, _ := token.Claims.(jwt.MapClaims)
+		if claims["typ"] != "access" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
 
 		jti, _ := claims["jti"].(string)
 		if jti != "" {
@@ -35,7 +61,39 @@ func AuthMiddleware() gin.HandlerFunc {
 			}
 		}
 
+		sid, _ := claims["sid"].(string)
+		if sid != "" {
+			key := "auth:session:revoked:" + sid
+			exists, _ := redis.AuthRedis.Exists(c.Request.Context(), key).Result()
+			if exists > 0 { // сессия завершена с другого устройства
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				return
+			}
+		}
+
 		c.Set("userID", uuid.MustParse(claims["id"].(string)))
+		if role, _ := claims["role"].(string); role != "" {
+			c.Set("role", role)
+		} else {
+			c.Set("role", models.RoleUser) // токены, выданные до появления ролей
+		}
+		if sessionID, err := uuid.Parse(sid); err == nil {
+			c.Set("sessionID", sessionID)
+		}
 		c.Next()
 	}
 }
+
+// RequireRole пропускает только пользователей с одной из ролей; ставится после AuthMiddleware
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	}
+}
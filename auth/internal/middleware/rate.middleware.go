@@ -9,6 +9,21 @@ import (
 )
 
 func RateLimiterMiddleware(redisClient *redis.Client, rateString string, prefix string) gin.HandlerFunc {
+	return newRateLimiter(redisClient, rateString, prefix)
+}
+
+// KeyedRateLimiterMiddleware ограничивает запросы по ключу из запроса (например, ID пользователя), а не по IP;
+// если ключ пустой, считается по IP
+func KeyedRateLimiterMiddleware(redisClient *redis.Client, rateString string, prefix string, key func(c *gin.Context) string) gin.HandlerFunc {
+	return newRateLimiter(redisClient, rateString, prefix, mgin.WithKeyGetter(func(c *gin.Context) string {
+		if k := key(c); k != "" {
+			return k
+		}
+		return c.ClientIP()
+	}))
+}
+
+func newRateLimiter(redisClient *redis.Client, rateString string, prefix string, options ...mgin.Option) gin.HandlerFunc {
 	rate, err := limiter.NewRateFromFormatted(rateString)
 	if err != nil {
 		panic("Invalid rate format: " + rateString + " → " + err.Error())
@@ -24,6 +39,6 @@ func RateLimiterMiddleware(redisClient *redis.Client, rateString string, prefix
 
 	lim := limiter.New(store, rate)
 
-	mw := mgin.NewMiddleware(lim)
+	mw := mgin.NewMiddleware(lim, options...)
 	return mw
 }
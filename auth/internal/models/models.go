@@ -13,22 +13,96 @@ type User struct {
 	Password   string    `json:"-" gorm:"not null"`
 	IsVerified bool      `json:"is_verified" gorm:"not null;default:false"`
 
+	Type    string     `json:"type" gorm:"size:10;not null;default:user"` // user или bot
+	OwnerID *uuid.UUID `json:"owner_id,omitempty" gorm:"type:uuid;index"` // владелец бота
+
+	Role string `json:"role" gorm:"size:20;not null;default:user"` // user, moderator или admin
+
+	// Блокировка: действует с SuspendedAt до SuspendedUntil (nil — бессрочно)
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty" gorm:"size:255"`
+
+	TOTPSecret  string `json:"-" gorm:"size:255"` // зашифрованный секрет (AES-GCM)
+	TOTPEnabled bool   `json:"totp_enabled" gorm:"not null;default:false"`
+
 	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	ToBeDeletedAt *time.Time     `json:"to_be_deleted_at" gorm:"index"`
 
 	RefreshTokens []RefreshToken `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	OTPCodes      []OTPCode      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	RecoveryCodes []RecoveryCode `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Passkeys      []Passkey      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Identities    []UserIdentity `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	SecurityEvents []SecurityEvent `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	APITokens      []APIToken      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	PasswordHistory []PasswordHistory `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TrustedDevices  []TrustedDevice   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+const (
+	UserTypeUser = "user"
+	UserTypeBot  = "bot"
+)
+
+// Роли пользователей; роль передаётся в claims access-токена
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || time.Now().Before(*u.SuspendedUntil))
 }
 
 type RefreshToken struct {
 	ID        uuid.UUID `json:"-" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
 	UserID    uuid.UUID `json:"-" gorm:"type:uuid;not null;index;"`
-	Token     string    `json:"-" gorm:"not null"`
-	ExpiresAt time.Time `json:"-" gorm:"not null"`
-	IP        string    `gorm:"size:45"`
-	UserAgent string    `gorm:"size:255"`
-	Device    string    `gorm:"size:100"`
+	Token     string    `json:"-" gorm:"-"`                   // исходное значение, в БД не сохраняется
+	TokenHash string    `json:"-" gorm:"size:64;uniqueIndex"` // SHA-256 от Token
+	ExpiresAt time.Time `json:"-" gorm:"not null;index"`      // по нему воркер удаляет истёкшие токены
+
+	// Ротация: все токены одной сессии образуют семейство, повторное
+	// предъявление уже ротированного токена отзывает всё семейство
+	FamilyID  uuid.UUID  `json:"-" gorm:"type:uuid;index"`
+	ParentID  *uuid.UUID `json:"-" gorm:"type:uuid"`
+	RotatedAt *time.Time `json:"-"`
+
+	IP        string `gorm:"size:45"` // адрес, с которого начата сессия
+	UserAgent string `gorm:"size:255"`
+	Device    string `gorm:"size:100"`
+
+	// Метаданные сессии переносятся в каждый следующий токен семейства
+	SessionCreatedAt time.Time `json:"-" gorm:"not null;default:now()"`
+	LastUsedAt       time.Time `json:"-" gorm:"not null;default:now()"`
+	LastIP           string    `json:"-" gorm:"size:45"`
+}
+
+// PasswordHistory — хеши прежних паролей, чтобы не допускать их повторного использования
+type PasswordHistory struct {
+	ID        uuid.UUID `json:"-" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID    uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	Hash      string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"-" gorm:"autoCreateTime"`
+}
+
+// TrustedDevice — браузер, на котором пользователь отметил «запомнить устройство»; вход с него не требует email-OTP.
+// Сам признак хранится в подписанной cookie с ID записи, удаление записи отзывает cookie
+type TrustedDevice struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Device    string    `json:"device" gorm:"size:100"`
+	IP        string    `json:"ip" gorm:"size:45"` // адрес, с которого устройство добавлено
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+
+	LastIP     string    `json:"last_ip" gorm:"size:45"`
+	LastUsedAt time.Time `json:"last_used_at" gorm:"not null;default:now()"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
 type OTPCode struct {
@@ -40,3 +114,109 @@ type OTPCode struct {
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
+
+type RecoveryCode struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID   uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash string    `json:"-" gorm:"not null;size:64;index"`
+
+	UsedAt    *time.Time `json:"used_at"`
+	UsedIP    string     `json:"used_ip" gorm:"size:45"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Passkey — WebAuthn-ключ пользователя (публичная часть и данные аутентификатора)
+type Passkey struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name         string    `json:"name" gorm:"size:100"`
+	CredentialID []byte    `json:"-" gorm:"not null;uniqueIndex"`
+	PublicKey    []byte    `json:"-" gorm:"not null"`
+
+	AttestationType string `json:"-" gorm:"size:32"`
+	AAGUID          []byte `json:"-"`
+	SignCount       uint32 `json:"sign_count" gorm:"not null;default:0"`
+	Transports      string `json:"transports" gorm:"size:100"` // через запятую: usb,nfc,ble,internal,hybrid
+	BackupEligible  bool   `json:"backup_eligible" gorm:"not null;default:false"`
+	BackupState     bool   `json:"backup_state" gorm:"not null;default:false"`
+
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// UserIdentity — привязанный аккаунт внешнего провайдера (OAuth2/OIDC)
+type UserIdentity struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID   uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_identity_user_provider"`
+	Provider string    `json:"provider" gorm:"not null;size:50;uniqueIndex:idx_identity_subject;uniqueIndex:idx_identity_user_provider"`
+	Subject  string    `json:"-" gorm:"not null;size:255;uniqueIndex:idx_identity_subject"` // claim sub у провайдера
+	Email    string    `json:"email" gorm:"size:255"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Типы событий журнала безопасности
+const (
+	EventLogin                = "login"
+	EventLoginFailed          = "login_failed"
+	EventPasswordChanged      = "password_changed"
+	EventSessionRevoked       = "session_revoked"
+	EventTrustedDeviceRevoked = "trusted_device_revoked"
+	EventDeletionScheduled    = "deletion_scheduled"
+	EventDeletionCancelled    = "deletion_cancelled"
+)
+
+// SecurityEvent — запись журнала безопасности пользователя
+type SecurityEvent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index:idx_security_events_user_time"`
+	Type      string    `json:"type" gorm:"not null;size:50"`
+	IP        string    `json:"ip" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	Device    string    `json:"device" gorm:"size:100"`
+	Details   string    `json:"details,omitempty" gorm:"size:255"`
+	NewDevice bool      `json:"new_device" gorm:"not null;default:false"` // вход с ранее не встречавшегося устройства/IP
+	// Повторы схлопываются в одну запись (неудачные входы с одного IP за час): сколько их было и когда последний
+	Count      int        `json:"count" gorm:"not null;default:1"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_security_events_user_time"`
+}
+
+// APIToken — персональный токен доступа для интеграций; в БД хранится только SHA-256
+type APIToken struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`       // от чьего имени действует токен (пользователь или бот)
+	CreatedByID uuid.UUID `json:"created_by_id" gorm:"type:uuid;not null;index"` // кто выпустил токен
+	Name        string    `json:"name" gorm:"not null;size:100"`
+	TokenHash   string    `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	Prefix      string    `json:"prefix" gorm:"size:16"`  // начало токена, чтобы его можно было узнать в списке
+	Scopes      string    `json:"scopes" gorm:"size:255"` // через запятую
+
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Действия администраторов
+const (
+	AuditVerify           = "verify"
+	AuditSuspend          = "suspend"
+	AuditUnsuspend        = "unsuspend"
+	AuditForceLogout      = "force_logout"
+	AuditScheduleDeletion = "schedule_deletion"
+	AuditCancelDeletion   = "cancel_deletion"
+	AuditChangeRole       = "change_role"
+)
+
+// AuditEntry — запись журнала действий администраторов; не удаляется вместе с пользователем
+type AuditEntry struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey; not null"`
+	ActorID  uuid.UUID `json:"actor_id" gorm:"type:uuid;not null;index"`
+	TargetID uuid.UUID `json:"target_id" gorm:"type:uuid;not null;index"`
+	Action   string    `json:"action" gorm:"not null;size:50"`
+	Details  string    `json:"details,omitempty" gorm:"size:255"`
+	IP       string    `json:"ip" gorm:"size:45"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
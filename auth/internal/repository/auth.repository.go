@@ -2,6 +2,8 @@ package repository
 
 import (
 	"auth/internal/models"
+	"auth/pkg/utils"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,17 +18,77 @@ type AuthRepository interface {
 	ScheduleDeletion(userID uuid.UUID, deletionTime time.Time) error
 	CancelDeletion(userID uuid.UUID) error
 	UpdateUser(user *models.User) error
+	FindExpiredDeletions(now time.Time) ([]models.User, error)
+	UpdatePassword(user *models.User, previousHash string, keepHistory int) error
+	UpdatePasswordHash(userID uuid.UUID, hash string) error
+	ListPasswordHistory(userID uuid.UUID, limit int) ([]models.PasswordHistory, error)
 
-	CreateRefreshToken(token string, userID uuid.UUID, expiresAt time.Time, ip, userAgent, device string) error
+	CreateRefreshToken(rt *models.RefreshToken) error
 	FindValidByToken(token string) (*models.RefreshToken, error)
+	FindByToken(token string) (*models.RefreshToken, error)
+	DeleteExpiredRefreshTokens(now time.Time) (int64, error)
+	MarkRotated(id uuid.UUID) (bool, error)
 	Revoke(token string) error
+	RevokeFamily(familyID uuid.UUID) error
 	RevokeAll(userID uuid.UUID) error
+	RevokeAllExcept(userID uuid.UUID, token string) error
 	ListActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error)
+	FindActiveSession(userID, sessionID uuid.UUID) (*models.RefreshToken, error)
 
 	CreateOTP(otp *models.OTPCode) error
 	FindValidOTP(userID uuid.UUID, code string) (*models.OTPCode, error)
 	MarkOTPAsUsed(id uuid.UUID) error
 	InvalidateAllActiveOTPs(userID uuid.UUID) error
+	DeleteAllOTPs(userID uuid.UUID) error
+
+	ReplaceRecoveryCodes(userID uuid.UUID, codes []models.RecoveryCode) error
+	DeleteRecoveryCodes(userID uuid.UUID) error
+	FindUnusedRecoveryCode(userID uuid.UUID, codeHash string) (*models.RecoveryCode, error)
+	MarkRecoveryCodeAsUsed(id uuid.UUID, ip string) error
+
+	CreatePasskey(pk *models.Passkey) error
+	ListPasskeys(userID uuid.UUID) ([]models.Passkey, error)
+	FindPasskeyByCredentialID(credentialID []byte) (*models.Passkey, error)
+	UpdatePasskeyUsage(id uuid.UUID, signCount uint32, backupState bool) error
+	DeletePasskey(userID, id uuid.UUID) error
+
+	CreateTrustedDevice(device *models.TrustedDevice) error
+	FindTrustedDevice(userID, id uuid.UUID) (*models.TrustedDevice, error)
+	ListTrustedDevices(userID uuid.UUID) ([]models.TrustedDevice, error)
+	UpdateTrustedDeviceUsage(id uuid.UUID, ip string) error
+	DeleteTrustedDevice(userID, id uuid.UUID) error
+	DeleteTrustedDevices(userID uuid.UUID) error
+	DeleteExpiredTrustedDevices(userID uuid.UUID) error
+
+	CreateIdentity(identity *models.UserIdentity) error
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+	FindIdentity(provider, subject string) (*models.UserIdentity, error)
+	ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error)
+	DeleteIdentity(userID uuid.UUID, provider string) error
+
+	CreateSecurityEvent(event *models.SecurityEvent) error
+	RepeatSecurityEvent(userID uuid.UUID, eventType, ip string, since time.Time) (bool, error)
+	ListSecurityEvents(userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, int64, error)
+	HasLogins(userID uuid.UUID) (bool, error)
+	HasLoginFrom(userID uuid.UUID, device, ip string) (bool, error)
+
+	ListBots(ownerID uuid.UUID) ([]models.User, error)
+
+	CreateAPIToken(token *models.APIToken) error
+	FindAPIToken(id uuid.UUID) (*models.APIToken, error)
+	FindActiveAPITokenByHash(hash string) (*models.APIToken, error)
+	ListAPITokensByCreator(creatorID uuid.UUID) ([]models.APIToken, error)
+	ListAPITokensByUser(userID uuid.UUID) ([]models.APIToken, error)
+	ListActiveAPITokens() ([]models.APIToken, error)
+	UpdateAPITokenLastUsed(id uuid.UUID, lastUsedAt time.Time) error
+	DeleteAPIToken(id uuid.UUID) error
+	DeleteAPITokensByUser(userID uuid.UUID) error
+
+	SearchUsers(email string, limit, offset int) ([]models.User, int64, error)
+	SetRoleByEmail(emails []string, role string) error
+	ListSuspendedUsers(now time.Time) ([]models.User, error)
+	CreateAuditEntry(entry *models.AuditEntry) error
+	ListAuditEntries(targetID *uuid.UUID, limit, offset int) ([]models.AuditEntry, int64, error)
 }
 type authRepository struct {
 	db *gorm.DB
@@ -89,45 +151,129 @@ func (r *authRepository) UpdateUser(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+func (r *authRepository) FindExpiredDeletions(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.
+		Where("to_be_deleted_at IS NOT NULL AND to_be_deleted_at <= ?", now).
+		Find(&users).Error
+	return users, err
+}
+
+// UpdatePasswordHash заменяет хеш того же пароля (перехеширование), не трогая историю
+func (r *authRepository) UpdatePasswordHash(userID uuid.UUID, hash string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password", hash).Error
+}
+
+// UpdatePassword сохраняет новый пароль и кладёт прежний хеш в историю, оставляя в ней keepHistory последних
+func (r *authRepository) UpdatePassword(user *models.User, previousHash string, keepHistory int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", user.Password).Error; err != nil {
+			return err
+		}
+		if keepHistory <= 0 {
+			return tx.Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error
+		}
+		if err := tx.Create(&models.PasswordHistory{UserID: user.ID, Hash: previousHash}).Error; err != nil {
+			return err
+		}
+		return tx.
+			Where("user_id = ? AND id NOT IN (?)", user.ID,
+				tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", user.ID).Order("created_at DESC").Limit(keepHistory)).
+			Delete(&models.PasswordHistory{}).Error
+	})
+}
+
+func (r *authRepository) ListPasswordHistory(userID uuid.UUID, limit int) ([]models.PasswordHistory, error) {
+	var history []models.PasswordHistory
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
 // ! RefreshToken
+// В БД хранится только SHA-256 от токена, поэтому все методы принимают исходное значение и хешируют его сами
 
-func (r *authRepository) CreateRefreshToken(token string, userID uuid.UUID, expiresAt time.Time, ip, userAgent, device string) error {
-	rt := models.RefreshToken{
-		Token:     token,
-		UserID:    userID,
-		ExpiresAt: expiresAt,
-		IP:        ip,
-		UserAgent: userAgent,
-		Device:    device,
-	}
-	return r.db.Create(&rt).Error
+func (r *authRepository) CreateRefreshToken(rt *models.RefreshToken) error {
+	rt.TokenHash = utils.HashToken(rt.Token)
+	return r.db.Create(rt).Error
 }
 
 func (r *authRepository) FindValidByToken(token string) (*models.RefreshToken, error) {
 	var rt models.RefreshToken
 	err := r.db.
-		Where("token = ? AND expires_at > ?", token, time.Now()).
+		Where("token_hash = ? AND expires_at > ? AND rotated_at IS NULL", utils.HashToken(token), time.Now()).
 		First(&rt).Error
 	if err != nil {
 		return nil, err
 	}
+	rt.Token = token
 
 	return &rt, nil
 }
 
+// FindByToken возвращает токен, даже если он уже был ротирован
+func (r *authRepository) FindByToken(token string) (*models.RefreshToken, error) {
+	var rt models.RefreshToken
+	err := r.db.
+		Where("token_hash = ? AND expires_at > ?", utils.HashToken(token), time.Now()).
+		First(&rt).Error
+	if err != nil {
+		return nil, err
+	}
+	rt.Token = token
+
+	return &rt, nil
+}
+
+// MarkRotated возвращает false, если токен уже был ротирован параллельным запросом
+func (r *authRepository) MarkRotated(id uuid.UUID) (bool, error) {
+	res := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// Revoke удаляет токен вместе со всем его семейством (сессией)
 func (r *authRepository) Revoke(token string) error {
-	return r.db.Where("token = ?", token).Delete(&models.RefreshToken{}).Error
+	hash := utils.HashToken(token)
+	return r.db.
+		Where("token_hash = ? OR family_id IN (?)", hash,
+			r.db.Model(&models.RefreshToken{}).Select("family_id").Where("token_hash = ?", hash)).
+		Delete(&models.RefreshToken{}).Error
+}
+
+func (r *authRepository) RevokeFamily(familyID uuid.UUID) error {
+	return r.db.Where("family_id = ?", familyID).Delete(&models.RefreshToken{}).Error
+}
+
+func (r *authRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	return res.RowsAffected, res.Error
 }
 
 func (r *authRepository) RevokeAll(userID uuid.UUID) error {
 	return r.db.Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error
 }
 
+func (r *authRepository) RevokeAllExcept(userID uuid.UUID, token string) error {
+	hash := utils.HashToken(token)
+	return r.db.
+		Where("user_id = ? AND token_hash <> ? AND (family_id IS NULL OR family_id NOT IN (?))", userID, hash,
+			r.db.Model(&models.RefreshToken{}).Select("family_id").Where("token_hash = ? AND family_id IS NOT NULL", hash)).
+		Delete(&models.RefreshToken{}).Error
+}
+
 func (r *authRepository) ListActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error) {
 	var sessions []models.RefreshToken
 	err := r.db.
-		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
-		Order("expires_at desc").
+		Where("user_id = ? AND expires_at > ? AND rotated_at IS NULL", userID, time.Now()).
+		Order("last_used_at desc").
 		Find(&sessions).Error
 	if err != nil {
 		return nil, err
@@ -135,6 +281,18 @@ func (r *authRepository) ListActiveSessions(userID uuid.UUID) ([]models.RefreshT
 	return sessions, nil
 }
 
+// FindActiveSession ищет текущий (не ротированный) токен сессии только среди токенов userID
+func (r *authRepository) FindActiveSession(userID, sessionID uuid.UUID) (*models.RefreshToken, error) {
+	var rt models.RefreshToken
+	err := r.db.
+		Where("family_id = ? AND user_id = ? AND expires_at > ? AND rotated_at IS NULL", sessionID, userID, time.Now()).
+		First(&rt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
 // ! OTP
 
 func (r *authRepository) CreateOTP(otp *models.OTPCode) error {
@@ -164,3 +322,359 @@ func (r *authRepository) InvalidateAllActiveOTPs(userID uuid.UUID) error {
 		Where("user_id = ? AND is_used = false AND expires_at > ?", userID, time.Now()).
 		Update("is_used", true).Error
 }
+
+func (r *authRepository) DeleteAllOTPs(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.OTPCode{}).Error
+}
+
+// ! Recovery codes
+
+func (r *authRepository) ReplaceRecoveryCodes(userID uuid.UUID, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *authRepository) DeleteRecoveryCodes(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r *authRepository) FindUnusedRecoveryCode(userID uuid.UUID, codeHash string) (*models.RecoveryCode, error) {
+	var code models.RecoveryCode
+	err := r.db.
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		First(&code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *authRepository) MarkRecoveryCodeAsUsed(id uuid.UUID, ip string) error {
+	res := r.db.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Updates(map[string]interface{}{"used_at": time.Now(), "used_ip": ip})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ! Passkeys
+
+func (r *authRepository) CreatePasskey(pk *models.Passkey) error {
+	return r.db.Create(pk).Error
+}
+
+func (r *authRepository) ListPasskeys(userID uuid.UUID) ([]models.Passkey, error) {
+	var passkeys []models.Passkey
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&passkeys).Error
+	return passkeys, err
+}
+
+func (r *authRepository) FindPasskeyByCredentialID(credentialID []byte) (*models.Passkey, error) {
+	var pk models.Passkey
+	if err := r.db.First(&pk, "credential_id = ?", credentialID).Error; err != nil {
+		return nil, err
+	}
+	return &pk, nil
+}
+
+func (r *authRepository) UpdatePasskeyUsage(id uuid.UUID, signCount uint32, backupState bool) error {
+	return r.db.Model(&models.Passkey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sign_count":   signCount,
+			"backup_state": backupState,
+			"last_used_at": time.Now(),
+		}).Error
+}
+
+// DeletePasskey удаляет ключ только если он принадлежит userID
+func (r *authRepository) DeletePasskey(userID, id uuid.UUID) error {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Passkey{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ! Доверенные устройства
+
+func (r *authRepository) CreateTrustedDevice(device *models.TrustedDevice) error {
+	return r.db.Create(device).Error
+}
+
+// FindTrustedDevice возвращает устройство, только если оно принадлежит userID и не истекло
+func (r *authRepository) FindTrustedDevice(userID, id uuid.UUID) (*models.TrustedDevice, error) {
+	var device models.TrustedDevice
+	err := r.db.First(&device, "id = ? AND user_id = ? AND expires_at > ?", id, userID, time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *authRepository) ListTrustedDevices(userID uuid.UUID) ([]models.TrustedDevice, error) {
+	var devices []models.TrustedDevice
+	err := r.db.
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func (r *authRepository) UpdateTrustedDeviceUsage(id uuid.UUID, ip string) error {
+	return r.db.Model(&models.TrustedDevice{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_ip":      ip,
+			"last_used_at": time.Now(),
+		}).Error
+}
+
+// DeleteTrustedDevice удаляет устройство только если оно принадлежит userID
+func (r *authRepository) DeleteTrustedDevice(userID, id uuid.UUID) error {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.TrustedDevice{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *authRepository) DeleteTrustedDevices(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.TrustedDevice{}).Error
+}
+
+func (r *authRepository) DeleteExpiredTrustedDevices(userID uuid.UUID) error {
+	return r.db.Where("user_id = ? AND expires_at <= ?", userID, time.Now()).Delete(&models.TrustedDevice{}).Error
+}
+
+// ! Внешние аккаунты (OAuth)
+
+func (r *authRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *authRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *authRepository) FindIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.First(&identity, "provider = ? AND subject = ?", provider, subject).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *authRepository) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *authRepository) DeleteIdentity(userID uuid.UUID, provider string) error {
+	res := r.db.Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.UserIdentity{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ! Журнал безопасности
+
+func (r *authRepository) CreateSecurityEvent(event *models.SecurityEvent) error {
+	return r.db.Create(event).Error
+}
+
+// RepeatSecurityEvent увеличивает счётчик события того же типа с того же IP, записанного после since;
+// false — такого события нет
+func (r *authRepository) RepeatSecurityEvent(userID uuid.UUID, eventType, ip string, since time.Time) (bool, error) {
+	result := r.db.Model(&models.SecurityEvent{}).
+		Where("user_id = ? AND type = ? AND ip = ? AND created_at > ?", userID, eventType, ip, since).
+		Updates(map[string]interface{}{
+			"count":        gorm.Expr("count + 1"),
+			"last_seen_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *authRepository) ListSecurityEvents(userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, int64, error) {
+	var events []models.SecurityEvent
+	var total int64
+
+	query := r.db.Model(&models.SecurityEvent{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+	return events, total, err
+}
+
+func (r *authRepository) HasLogins(userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SecurityEvent{}).
+		Where("user_id = ? AND type = ?", userID, models.EventLogin).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *authRepository) HasLoginFrom(userID uuid.UUID, device, ip string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SecurityEvent{}).
+		Where("user_id = ? AND type = ? AND device = ? AND ip = ?", userID, models.EventLogin, device, ip).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ! Боты и персональные токены
+
+func (r *authRepository) ListBots(ownerID uuid.UUID) ([]models.User, error) {
+	var bots []models.User
+	err := r.db.Where("owner_id = ? AND type = ?", ownerID, models.UserTypeBot).Order("created_at ASC").Find(&bots).Error
+	return bots, err
+}
+
+func (r *authRepository) CreateAPIToken(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *authRepository) FindAPIToken(id uuid.UUID) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := r.db.First(&token, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindActiveAPITokenByHash ищет неистёкший токен по хешу
+func (r *authRepository) FindActiveAPITokenByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *authRepository) ListAPITokensByCreator(creatorID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("created_by_id = ?", creatorID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *authRepository) ListAPITokensByUser(userID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ?", userID).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *authRepository) ListActiveAPITokens() ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *authRepository) UpdateAPITokenLastUsed(id uuid.UUID, lastUsedAt time.Time) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+}
+
+func (r *authRepository) DeleteAPIToken(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.APIToken{}).Error
+}
+
+func (r *authRepository) DeleteAPITokensByUser(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error
+}
+
+// ! Администрирование
+
+// SearchUsers ищет пользователей (не ботов) по части email без учёта регистра
+func (r *authRepository) SearchUsers(email string, limit, offset int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := r.db.Model(&models.User{}).Where("type = ?", models.UserTypeUser)
+	if email != "" {
+		query = query.Where("email ILIKE ?", "%"+escapeLike(email)+"%")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Order("email").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	return users, total, err
+}
+
+func (r *authRepository) SetRoleByEmail(emails []string, role string) error {
+	return r.db.Model(&models.User{}).Where("email IN ?", emails).Update("role", role).Error
+}
+
+// ListSuspendedUsers возвращает пользователей, блокировка которых ещё действует
+func (r *authRepository) ListSuspendedUsers(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.
+		Where("suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > ?)", now).
+		Find(&users).Error
+	return users, err
+}
+
+func (r *authRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// ListAuditEntries возвращает журнал целиком или только по одному пользователю (targetID)
+func (r *authRepository) ListAuditEntries(targetID *uuid.UUID, limit, offset int) ([]models.AuditEntry, int64, error) {
+	var entries []models.AuditEntry
+	var total int64
+
+	query := r.db.Model(&models.AuditEntry{})
+	if targetID != nil {
+		query = query.Where("target_id = ?", *targetID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
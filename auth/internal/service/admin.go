@@ -0,0 +1,223 @@
+package service
+
+import (
+	"auth/config"
+	"auth/internal/models"
+	"auth/pkg/rabbitmq"
+	"auth/pkg/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeletionGracePeriod — сколько аккаунт ждёт окончательного удаления после запроса
+const DeletionGracePeriod = 3 * 24 * time.Hour
+
+// EnsureAdmins выдаёт роль admin адресам из ADMIN_EMAILS; вызывается при старте, чтобы было кому назначать роли
+func (s *authService) EnsureAdmins() error {
+	if len(config.Env.AdminEmails) == 0 {
+		return nil
+	}
+	emails := make([]string, 0, len(config.Env.AdminEmails))
+	for _, email := range config.Env.AdminEmails {
+		emails = append(emails, utils.NormalizeEmail(email))
+	}
+	return s.repo.SetRoleByEmail(emails, models.RoleAdmin)
+}
+
+func (s *authService) SearchUsers(email string, limit, offset int) ([]models.User, int64, error) {
+	return s.repo.SearchUsers(email, limit, offset)
+}
+
+func (s *authService) ListAuditEntries(targetID *uuid.UUID, limit, offset int) ([]models.AuditEntry, int64, error) {
+	return s.repo.ListAuditEntries(targetID, limit, offset)
+}
+
+// ! Действия над аккаунтом
+
+func (s *authService) AdminVerifyUser(actorID, targetID uuid.UUID, ip string) error {
+	user, err := s.repo.FindByID(targetID)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified {
+		return errors.New("user is already verified")
+	}
+	user.IsVerified = true
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	s.audit(actorID, targetID, models.AuditVerify, "", ip)
+	return nil
+}
+
+// AdminSuspendUser блокирует аккаунт до until (nil — бессрочно): вход и обновление токенов запрещаются,
+// сессии завершаются, персональные токены пользователя и его ботов перестают действовать.
+// Остальные сервисы узнают о блокировке из Redis и события user_suspended
+func (s *authService) AdminSuspendUser(actorID, targetID uuid.UUID, reason string, until *time.Time, ip string) error {
+	if until != nil && !until.After(time.Now()) {
+		return errors.New("suspension end must be in the future")
+	}
+	user, err := s.adminTarget(actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if user.IsSuspended() {
+		return errors.New("user is already suspended")
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	user.SuspendedUntil = until
+	user.SuspensionReason = reason
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	bots, err := s.markUserSuspended(user)
+	if err != nil {
+		log.Printf("[Admin] Failed to mark user %s as suspended: %v", targetID, err)
+	}
+	if err := s.RevokeAllRefreshTokens(targetID); err != nil {
+		log.Printf("[Admin] Failed to revoke sessions of suspended user %s: %v", targetID, err)
+	}
+
+	extra := map[string]interface{}{"reason": reason}
+	details := reason
+	if until != nil {
+		extra["until"] = until
+		details += " (until " + until.Format(time.RFC3339) + ")"
+	}
+	if err := rabbitmq.PublishUserEvent(targetID, "user_suspended", extra); err != nil {
+		log.Printf("[Admin] Failed to publish user_suspended event for %s: %v", targetID, err)
+	}
+	// Боты могут держать WebSocket — закрываем и их
+	for _, bot := range bots {
+		if err := rabbitmq.PublishUserEvent(bot.ID, "user_suspended", extra); err != nil {
+			log.Printf("[Admin] Failed to publish user_suspended event for bot %s: %v", bot.ID, err)
+		}
+	}
+	s.audit(actorID, targetID, models.AuditSuspend, details, ip)
+	return nil
+}
+
+// AdminUnsuspendUser снимает блокировку. События нет: сервисы проверяют отметку в Redis при каждом запросе
+// и подключении, так что после её удаления пользователь сразу снова может работать
+func (s *authService) AdminUnsuspendUser(actorID, targetID uuid.UUID, ip string) error {
+	user, err := s.repo.FindByID(targetID)
+	if err != nil {
+		return err
+	}
+	if !user.IsSuspended() {
+		return errors.New("user is not suspended")
+	}
+	user.SuspendedAt = nil
+	user.SuspendedUntil = nil
+	user.SuspensionReason = ""
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	if err := s.clearUserSuspended(targetID); err != nil {
+		log.Printf("[Admin] Failed to clear suspension of %s: %v", targetID, err)
+	}
+	s.audit(actorID, targetID, models.AuditUnsuspend, "", ip)
+	return nil
+}
+
+func (s *authService) AdminForceLogout(actorID, targetID uuid.UUID, ip string) error {
+	if _, err := s.repo.FindByID(targetID); err != nil {
+		return err
+	}
+	if err := s.RevokeAllRefreshTokens(targetID); err != nil {
+		return err
+	}
+	s.audit(actorID, targetID, models.AuditForceLogout, "", ip)
+	return nil
+}
+
+func (s *authService) AdminScheduleDeletion(actorID, targetID uuid.UUID, ip string) (time.Time, error) {
+	user, err := s.adminTarget(actorID, targetID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.ToBeDeletedAt != nil {
+		return time.Time{}, errors.New("deletion is already scheduled")
+	}
+
+	deletionTime := time.Now().Add(DeletionGracePeriod)
+	if err := s.repo.ScheduleDeletion(targetID, deletionTime); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.RevokeAllRefreshTokens(targetID); err != nil {
+		log.Printf("[Admin] Failed to revoke sessions of %s: %v", targetID, err)
+	}
+	s.RecordSecurityEvent(targetID, models.EventDeletionScheduled, ip, "", "admin")
+	s.audit(actorID, targetID, models.AuditScheduleDeletion, deletionTime.Format(time.RFC3339), ip)
+	return deletionTime, nil
+}
+
+func (s *authService) AdminCancelDeletion(actorID, targetID uuid.UUID, ip string) error {
+	user, err := s.repo.FindByID(targetID)
+	if err != nil {
+		return err
+	}
+	if user.ToBeDeletedAt == nil {
+		return errors.New("deletion is not scheduled")
+	}
+	if err := s.repo.CancelDeletion(targetID); err != nil {
+		return err
+	}
+	s.RecordSecurityEvent(targetID, models.EventDeletionCancelled, ip, "", "admin")
+	s.audit(actorID, targetID, models.AuditCancelDeletion, "", ip)
+	return nil
+}
+
+// AdminSetRole меняет роль; новая роль попадает в токены после обновления, поэтому сессии завершаются сразу
+func (s *authService) AdminSetRole(actorID, targetID uuid.UUID, role, ip string) error {
+	if role != models.RoleUser && role != models.RoleModerator && role != models.RoleAdmin {
+		return errors.New("unknown role")
+	}
+	user, err := s.adminTarget(actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if user.Type == models.UserTypeBot {
+		return errors.New("bots cannot have roles")
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	previous := user.Role
+	user.Role = role
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	if err := s.RevokeAllRefreshTokens(targetID); err != nil {
+		log.Printf("[Admin] Failed to revoke sessions of %s after role change: %v", targetID, err)
+	}
+	s.audit(actorID, targetID, models.AuditChangeRole, previous+" -> "+role, ip)
+	return nil
+}
+
+// adminTarget загружает пользователя для действия, которое администратор не может применить к себе
+func (s *authService) adminTarget(actorID, targetID uuid.UUID) (*models.User, error) {
+	if actorID == targetID {
+		return nil, errors.New("cannot apply to own account")
+	}
+	return s.repo.FindByID(targetID)
+}
+
+func (s *authService) audit(actorID, targetID uuid.UUID, action, details, ip string) {
+	entry := models.AuditEntry{
+		ActorID:  actorID,
+		TargetID: targetID,
+		Action:   action,
+		Details:  details,
+		IP:       ip,
+	}
+	if err := s.repo.CreateAuditEntry(&entry); err != nil {
+		log.Printf("[Admin] Failed to record %s by %s on %s: %v", action, actorID, targetID, err)
+	}
+}
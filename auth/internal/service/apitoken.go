@@ -0,0 +1,292 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/rabbitmq"
+	"auth/pkg/redis"
+	"auth/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Персональные токены проверяются через Redis, источник истины — БД:
+// auth:pat:<sha256> — описание токена (при промахе восстанавливается из БД),
+// auth:pat:missing:<sha256> — токена нет и в БД, повторно не ищем,
+// auth:pat:used:<id> — время последнего использования (unix), в БД переносится не чаще apiTokenUsageFlushInterval
+
+type apiTokenRecord struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Scopes []string  `json:"scopes"`
+}
+
+const maxBotsPerUser = 10
+
+const (
+	apiTokenMissingTTL         = 5 * time.Minute
+	apiTokenUsageFlushInterval = 10 * time.Minute
+)
+
+// ! Боты
+
+func (s *authService) CreateBot(ownerID uuid.UUID, name string) (*models.User, error) {
+	owner, err := s.repo.FindByID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Type == models.UserTypeBot {
+		return nil, errors.New("bots cannot create bots")
+	}
+	bots, err := s.repo.ListBots(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) >= maxBotsPerUser {
+		return nil, errors.New("bot limit reached")
+	}
+
+	// Бот не входит по паролю и почте: адрес служебный, пароль неизвестен
+	id := uuid.New()
+	hash, err := s.hasher.Hash(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+	bot := models.User{
+		ID:         id,
+		Email:      "bot-" + id.String() + "@bots.livechat.local",
+		Password:   hash,
+		IsVerified: true,
+		Type:       models.UserTypeBot,
+		OwnerID:    &ownerID,
+	}
+	if err := s.repo.CreateUser(&bot); err != nil {
+		return nil, err
+	}
+
+	// Профиль бота создаётся в user-сервисе так же, как для обычного пользователя
+	err = rabbitmq.PublishUserEvent(bot.ID, "user_created", map[string]interface{}{
+		"type": models.UserTypeBot,
+		"name": name,
+	})
+	if err != nil {
+		log.Printf("[Bots] Failed to publish user_created event for bot %s: %v", bot.ID, err)
+	}
+	return &bot, nil
+}
+
+func (s *authService) ListBots(ownerID uuid.UUID) ([]models.User, error) {
+	return s.repo.ListBots(ownerID)
+}
+
+func (s *authService) DeleteBot(ownerID, botID uuid.UUID) error {
+	bot, err := s.repo.FindByID(botID)
+	if err != nil {
+		return err
+	}
+	if bot.Type != models.UserTypeBot || bot.OwnerID == nil || *bot.OwnerID != ownerID {
+		return gorm.ErrRecordNotFound
+	}
+	return s.purgeUser(bot.ID)
+}
+
+// ! Персональные токены
+
+// CreateAPIToken выпускает токен для самого пользователя или для его бота (botID); открытое значение возвращается один раз
+func (s *authService) CreateAPIToken(creatorID uuid.UUID, botID *uuid.UUID, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error) {
+	for _, scope := range scopes {
+		if !utils.ValidAPITokenScope(scope) {
+			return "", nil, errors.New("unknown scope: " + scope)
+		}
+	}
+
+	subjectID := creatorID
+	if botID != nil {
+		bot, err := s.repo.FindByID(*botID)
+		if err != nil || bot.Type != models.UserTypeBot || bot.OwnerID == nil || *bot.OwnerID != creatorID {
+			return "", nil, errors.New("bot not found")
+		}
+		subjectID = bot.ID
+	}
+
+	plain := utils.GenerateAPIToken()
+	token := models.APIToken{
+		UserID:      subjectID,
+		CreatedByID: creatorID,
+		Name:        name,
+		TokenHash:   utils.HashToken(plain),
+		Prefix:      plain[:len(utils.APITokenPrefix)+6],
+		Scopes:      strings.Join(scopes, ","),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateAPIToken(&token); err != nil {
+		return "", nil, err
+	}
+	if err := cacheAPIToken(&token); err != nil {
+		_ = s.repo.DeleteAPIToken(token.ID)
+		return "", nil, err
+	}
+	return plain, &token, nil
+}
+
+// ListAPITokens возвращает токены, выпущенные пользователем (в том числе для его ботов)
+func (s *authService) ListAPITokens(creatorID uuid.UUID) ([]models.APIToken, error) {
+	tokens, err := s.repo.ListAPITokensByCreator(creatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Время использования пишут все сервисы в Redis; переносим его в БД при чтении
+	ctx := context.Background()
+	for i := range tokens {
+		raw, err := redis.AuthRedis.Get(ctx, "auth:pat:used:"+tokens[i].ID.String()).Result()
+		if err != nil {
+			continue
+		}
+		unix, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+		usedAt := time.Unix(unix, 0)
+		if tokens[i].LastUsedAt == nil || usedAt.After(*tokens[i].LastUsedAt) {
+			tokens[i].LastUsedAt = &usedAt
+			_ = s.repo.UpdateAPITokenLastUsed(tokens[i].ID, usedAt)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *authService) RevokeAPIToken(creatorID, tokenID uuid.UUID) error {
+	token, err := s.repo.FindAPIToken(tokenID)
+	if err != nil {
+		return err
+	}
+	if token.CreatedByID != creatorID && token.UserID != creatorID {
+		return gorm.ErrRecordNotFound
+	}
+	// Сначала БД: иначе параллельный запрос успел бы восстановить запись в Redis
+	if err := s.repo.DeleteAPIToken(token.ID); err != nil {
+		return err
+	}
+	uncacheAPIToken(token)
+	return nil
+}
+
+// SyncAPITokens восстанавливает записи в Redis по БД, например после перезапуска Redis
+func (s *authService) SyncAPITokens() error {
+	tokens, err := s.repo.ListActiveAPITokens()
+	if err != nil {
+		return err
+	}
+	for i := range tokens {
+		if err := cacheAPIToken(&tokens[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeUserAPITokens удаляет все токены пользователя из БД и Redis
+func (s *authService) revokeUserAPITokens(userID uuid.UUID) error {
+	tokens, err := s.repo.ListAPITokensByUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAPITokensByUser(userID); err != nil {
+		return err
+	}
+	for i := range tokens {
+		uncacheAPIToken(&tokens[i])
+	}
+	return nil
+}
+
+func cacheAPIToken(token *models.APIToken) error {
+	var ttl time.Duration
+	if token.ExpiresAt != nil {
+		ttl = time.Until(*token.ExpiresAt)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	data, _ := json.Marshal(newAPITokenRecord(token))
+	return redis.AuthRedis.Set(context.Background(), "auth:pat:"+token.TokenHash, data, ttl).Err()
+}
+
+func newAPITokenRecord(token *models.APIToken) *apiTokenRecord {
+	var scopes []string
+	if token.Scopes != "" {
+		scopes = strings.Split(token.Scopes, ",")
+	}
+	return &apiTokenRecord{ID: token.ID, UserID: token.UserID, Scopes: scopes}
+}
+
+// findAPIToken ищет токен по хешу в Redis, при промахе — в БД, и возвращает оставшийся срок (0 — бессрочный).
+// nil без ошибки — токена нет
+func (s *authService) findAPIToken(hash string) (*apiTokenRecord, time.Duration, error) {
+	ctx := context.Background()
+	key := "auth:pat:" + hash
+	raw, err := redis.AuthRedis.Get(ctx, key).Bytes()
+	if err == nil {
+		var record apiTokenRecord
+		if err := json.Unmarshal(raw, &record); err == nil {
+			ttl, _ := redis.AuthRedis.TTL(ctx, key).Result()
+			if ttl < 0 {
+				ttl = 0
+			}
+			return &record, ttl, nil
+		}
+	}
+	if missing, _ := redis.AuthRedis.Exists(ctx, "auth:pat:missing:"+hash).Result(); missing > 0 {
+		return nil, 0, nil
+	}
+
+	// Запись могла пропасть при перезапуске или вытеснении в Redis — проверяем БД
+	token, err := s.repo.FindActiveAPITokenByHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Хеш случайного токена не может совпасть с будущим, поэтому отрицательный ответ можно запомнить
+		redis.AuthRedis.Set(ctx, "auth:pat:missing:"+hash, "1", apiTokenMissingTTL)
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cacheAPIToken(token); err != nil {
+		log.Printf("[APIToken] Failed to cache token %s: %v", token.ID, err)
+	}
+	var ttl time.Duration
+	if token.ExpiresAt != nil {
+		ttl = time.Until(*token.ExpiresAt)
+	}
+	return newAPITokenRecord(token), ttl, nil
+}
+
+// touchAPIToken отмечает использование в Redis и не чаще apiTokenUsageFlushInterval переносит его в БД
+func (s *authService) touchAPIToken(id uuid.UUID) {
+	ctx := context.Background()
+	now := time.Now()
+	redis.AuthRedis.Set(ctx, "auth:pat:used:"+id.String(), strconv.FormatInt(now.Unix(), 10), 90*24*time.Hour)
+
+	flush, err := redis.AuthRedis.SetNX(ctx, "auth:pat:flush:"+id.String(), "1", apiTokenUsageFlushInterval).Result()
+	if err != nil || !flush {
+		return
+	}
+	if err := s.repo.UpdateAPITokenLastUsed(id, now); err != nil {
+		log.Printf("[APIToken] Failed to save last use of token %s: %v", id, err)
+	}
+}
+
+func uncacheAPIToken(token *models.APIToken) {
+	redis.AuthRedis.Del(context.Background(), "auth:pat:"+token.TokenHash, "auth:pat:used:"+token.ID.String(), "auth:pat:flush:"+token.ID.String())
+}
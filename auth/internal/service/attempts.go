@@ -0,0 +1,140 @@
+package service
+
+import (
+	"auth/pkg/redis"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Ошибки считаются отдельно для пары (scope, IP) и для scope целиком. По паре блокировка наступает быстро,
+// но затрагивает только этот адрес — чужой аккаунт так не заблокировать. Scope целиком блокируется
+// только при переборе с многих адресов
+const (
+	maxFailedAttempts        = 5              // после стольких ошибок подряд с одного IP он блокируется
+	maxAccountFailedAttempts = 50             // после стольких ошибок со всех IP блокируется весь scope
+	maxOTPAttempts           = 5              // после стольких ошибок OTP-код аннулируется
+	failureWindow            = time.Hour      // сколько хранится счётчик ошибок с момента последней
+	baseLockDuration         = time.Minute    // первая блокировка, дальше удваивается
+	maxLockDuration          = 24 * time.Hour // верхняя граница блокировки
+)
+
+// LockedError — слишком много неудачных попыток, повторить можно после Until
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, locked until %s", e.Until.Format(time.RFC3339))
+}
+
+// attemptStore хранит счётчики ошибок и блокировки
+type attemptStore interface {
+	// Incr увеличивает счётчик и продлевает его жизнь до ttl
+	Incr(key string, ttl time.Duration) (int64, error)
+	Set(key string, ttl time.Duration) error
+	// TTL — сколько ещё живёт ключ; 0, если его нет
+	TTL(key string) time.Duration
+	Del(keys ...string)
+}
+
+// attempts — общий для всех реплик Redis; в тестах подменяется хранилищем в памяти
+var attempts attemptStore = redisAttempts{}
+
+type redisAttempts struct{}
+
+func (redisAttempts) Incr(key string, ttl time.Duration) (int64, error) {
+	ctx := context.Background()
+	count, err := redis.AuthRedis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	redis.AuthRedis.Expire(ctx, key, ttl)
+	return count, nil
+}
+
+func (redisAttempts) Set(key string, ttl time.Duration) error {
+	return redis.AuthRedis.Set(context.Background(), key, "1", ttl).Err()
+}
+
+func (redisAttempts) TTL(key string) time.Duration {
+	ttl, err := redis.AuthRedis.PTTL(context.Background(), key).Result()
+	if err != nil || ttl <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+func (redisAttempts) Del(keys ...string) {
+	redis.AuthRedis.Del(context.Background(), keys...)
+}
+
+// checkLock возвращает *LockedError, если заблокирован scope (например "login:<email>") или пара scope и ip
+func checkLock(scope, ip string) error {
+	for _, key := range []string{scopeWithIP(scope, ip), scope} {
+		if ttl := attempts.TTL("auth:lock:" + key); ttl > 0 {
+			return &LockedError{Until: time.Now().Add(ttl)}
+		}
+	}
+	return nil
+}
+
+// registerFailure считает ошибку для пары scope и ip и для scope целиком; при превышении лимита
+// блокирует с экспоненциально растущей длительностью
+func registerFailure(scope, ip string) error {
+	if err := countFailure(scopeWithIP(scope, ip), maxFailedAttempts); err != nil {
+		return err
+	}
+	return countFailure(scope, maxAccountFailedAttempts)
+}
+
+func countFailure(key string, limit int64) error {
+	count, err := attempts.Incr("auth:attempts:"+key, failureWindow)
+	if err != nil {
+		return err
+	}
+	if count < limit {
+		return nil
+	}
+
+	lock := lockDuration(count - limit)
+	if err := attempts.Set("auth:lock:"+key, lock); err != nil {
+		return err
+	}
+	return &LockedError{Until: time.Now().Add(lock)}
+}
+
+// lockDuration — длительность блокировки после excess ошибок сверх лимита: 1, 2, 4... минут, не больше суток
+func lockDuration(excess int64) time.Duration {
+	lock := time.Duration(float64(baseLockDuration) * math.Pow(2, float64(excess)))
+	if lock > maxLockDuration || lock <= 0 {
+		return maxLockDuration
+	}
+	return lock
+}
+
+// resetFailures вызывается после успешной проверки: снимает счётчики и блокировки пары и scope
+func resetFailures(scope, ip string) {
+	withIP := scopeWithIP(scope, ip)
+	attempts.Del("auth:attempts:"+withIP, "auth:lock:"+withIP, "auth:attempts:"+scope, "auth:lock:"+scope)
+}
+
+func scopeWithIP(scope, ip string) string {
+	return scope + ":ip:" + ip
+}
+
+// countOTPFailure считает ошибки ввода OTP по пользователю со всех адресов и возвращает true, если активные коды
+// нужно аннулировать. Счётчик не сбрасывается при отправке нового кода: иначе повторная отправка
+// давала бы новую серию попыток
+func countOTPFailure(userID string) bool {
+	count, err := attempts.Incr("auth:attempts:otp:"+userID, failureWindow)
+	if err != nil {
+		return false
+	}
+	return count >= maxOTPAttempts
+}
+
+func resetOTPFailures(userID string) {
+	attempts.Del("auth:attempts:otp:" + userID)
+}
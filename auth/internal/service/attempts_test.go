@@ -0,0 +1,411 @@
+package service
+
+import (
+	"auth/config"
+	"auth/internal/models"
+	"auth/internal/repository"
+	"auth/pkg/mailer"
+	"auth/pkg/utils"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryAttempts — хранилище счётчиков в памяти со сдвигаемыми часами вместо Redis
+type memoryAttempts struct {
+	now  time.Time
+	keys map[string]memoryAttempt
+}
+
+type memoryAttempt struct {
+	count   int64
+	expires time.Time
+}
+
+func newMemoryAttempts(t *testing.T) *memoryAttempts {
+	t.Helper()
+	m := &memoryAttempts{now: time.Now(), keys: map[string]memoryAttempt{}}
+	prev := attempts
+	attempts = m
+	t.Cleanup(func() { attempts = prev })
+	return m
+}
+
+func (m *memoryAttempts) get(key string) (memoryAttempt, bool) {
+	a, ok := m.keys[key]
+	if ok && !m.now.Before(a.expires) {
+		delete(m.keys, key)
+		return memoryAttempt{}, false
+	}
+	return a, ok
+}
+
+func (m *memoryAttempts) Incr(key string, ttl time.Duration) (int64, error) {
+	a, _ := m.get(key)
+	a.count++
+	a.expires = m.now.Add(ttl)
+	m.keys[key] = a
+	return a.count, nil
+}
+
+func (m *memoryAttempts) Set(key string, ttl time.Duration) error {
+	m.keys[key] = memoryAttempt{count: 1, expires: m.now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryAttempts) TTL(key string) time.Duration {
+	a, ok := m.get(key)
+	if !ok {
+		return 0
+	}
+	return a.expires.Sub(m.now)
+}
+
+func (m *memoryAttempts) Del(keys ...string) {
+	for _, key := range keys {
+		delete(m.keys, key)
+	}
+}
+
+func (m *memoryAttempts) advance(d time.Duration) {
+	m.now = m.now.Add(d)
+}
+
+// failTimes регистрирует n ошибок и возвращает результат последней
+func failTimes(scope, ip string, n int) error {
+	var err error
+	for i := 0; i < n; i++ {
+		err = registerFailure(scope, ip)
+	}
+	return err
+}
+
+func isLocked(err error) bool {
+	var locked *LockedError
+	return errors.As(err, &locked)
+}
+
+func TestLockoutThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		ip          string // откуда проверяется блокировка после ошибок с 10.0.0.1
+		wantLastErr bool
+		wantLocked  bool
+	}{
+		{"below limit", maxFailedAttempts - 1, "10.0.0.1", false, false},
+		{"limit reached", maxFailedAttempts, "10.0.0.1", true, true},
+		{"other ip unaffected", maxFailedAttempts, "10.0.0.2", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newMemoryAttempts(t)
+			if err := failTimes("login:user@example.com", "10.0.0.1", tt.failures); isLocked(err) != tt.wantLastErr {
+				t.Fatalf("last registerFailure = %v, want locked %v", err, tt.wantLastErr)
+			}
+			if err := checkLock("login:user@example.com", tt.ip); isLocked(err) != tt.wantLocked {
+				t.Fatalf("checkLock(%s) = %v, want locked %v", tt.ip, err, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestAccountLockoutAcrossIPs(t *testing.T) {
+	newMemoryAttempts(t)
+	const scope = "login:user@example.com"
+
+	// По одной ошибке с разных адресов: ни один адрес не блокируется, пока не исчерпан общий лимит
+	for i := 0; i < maxAccountFailedAttempts-1; i++ {
+		ip := fmt.Sprintf("10.0.1.%d", i)
+		if err := registerFailure(scope, ip); err != nil {
+			t.Fatalf("failure %d from %s: %v", i+1, ip, err)
+		}
+	}
+	if err := checkLock(scope, "192.168.0.1"); err != nil {
+		t.Fatalf("account locked before the limit: %v", err)
+	}
+
+	if err := registerFailure(scope, "192.168.0.1"); !isLocked(err) {
+		t.Fatalf("failure over account limit = %v, want LockedError", err)
+	}
+	if err := checkLock(scope, "192.168.0.2"); !isLocked(err) {
+		t.Fatalf("account not locked for a fresh IP: %v", err)
+	}
+	if err := checkLock("login:other@example.com", "192.168.0.1"); err != nil {
+		t.Fatalf("other account locked: %v", err)
+	}
+}
+
+func TestLockDuration(t *testing.T) {
+	tests := []struct {
+		excess int64
+		want   time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{10, 1024 * time.Minute},
+		{11, maxLockDuration},
+		{200, maxLockDuration},
+	}
+	for _, tt := range tests {
+		if got := lockDuration(tt.excess); got != tt.want {
+			t.Errorf("lockDuration(%d) = %s, want %s", tt.excess, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutExpiry(t *testing.T) {
+	store := newMemoryAttempts(t)
+	const scope, ip = "verify:user", "10.0.0.1"
+
+	failTimes(scope, ip, maxFailedAttempts)
+	store.advance(baseLockDuration - time.Second)
+	if err := checkLock(scope, ip); !isLocked(err) {
+		t.Fatalf("lock released early: %v", err)
+	}
+	store.advance(time.Second)
+	if err := checkLock(scope, ip); err != nil {
+		t.Fatalf("lock not released after %s: %v", baseLockDuration, err)
+	}
+
+	// Счётчик живёт дольше блокировки: следующая ошибка сразу блокирует на удвоенный срок
+	if err := registerFailure(scope, ip); !isLocked(err) {
+		t.Fatalf("failure after lock expiry = %v, want LockedError", err)
+	}
+	store.advance(2*baseLockDuration - time.Second)
+	if err := checkLock(scope, ip); !isLocked(err) {
+		t.Fatal("second lock is not doubled")
+	}
+
+	// Без ошибок в течение failureWindow счётчик пропадает
+	store.advance(failureWindow)
+	if err := failTimes(scope, ip, maxFailedAttempts-1); err != nil {
+		t.Fatalf("counter survived failureWindow: %v", err)
+	}
+}
+
+func TestResetFailures(t *testing.T) {
+	newMemoryAttempts(t)
+	const scope, ip = "login:user@example.com", "10.0.0.1"
+
+	failTimes(scope, ip, maxFailedAttempts)
+	resetFailures(scope, ip)
+	if err := checkLock(scope, ip); err != nil {
+		t.Fatalf("lock survived reset: %v", err)
+	}
+	if err := failTimes(scope, ip, maxFailedAttempts-1); err != nil {
+		t.Fatalf("counter survived reset: %v", err)
+	}
+}
+
+// otpRepo хранит OTP-коды в памяти
+type otpRepo struct {
+	repository.AuthRepository
+	codes []models.OTPCode
+}
+
+func (r *otpRepo) CreateOTP(otp *models.OTPCode) error {
+	otp.ID = uuid.New()
+	r.codes = append(r.codes, *otp)
+	return nil
+}
+
+func (r *otpRepo) InvalidateAllActiveOTPs(userID uuid.UUID) error {
+	for i := range r.codes {
+		if r.codes[i].UserID == userID {
+			r.codes[i].IsUsed = true
+		}
+	}
+	return nil
+}
+
+func (r *otpRepo) FindValidOTP(userID uuid.UUID, code string) (*models.OTPCode, error) {
+	for i := range r.codes {
+		c := r.codes[i]
+		if c.UserID == userID && c.Code == code && !c.IsUsed && time.Now().Before(c.ExpiresAt) {
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type discardMailer struct{}
+
+func (discardMailer) Send(mailer.Message) error { return nil }
+
+func TestOTPFailuresSurviveResend(t *testing.T) {
+	newMemoryAttempts(t)
+	s := &authService{repo: &otpRepo{}, mailer: discardMailer{}}
+	userID := uuid.New()
+
+	// Ошибки с разных адресов, чтобы не упереться в блокировку по IP
+	for i := 0; i < maxOTPAttempts-1; i++ {
+		if _, _, err := s.SendOTP(userID, "user@example.com", "en"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindValidOTP(userID, "wrong", fmt.Sprintf("10.0.0.%d", i)); err == nil {
+			t.Fatal("wrong code accepted")
+		}
+	}
+
+	code, _, err := s.SendOTP(userID, "user@example.com", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindValidOTP(userID, "wrong", "10.0.1.1"); err == nil {
+		t.Fatal("wrong code accepted")
+	}
+	if _, err := s.FindValidOTP(userID, code, "10.0.1.2"); err == nil {
+		t.Fatal("code is still valid after the OTP attempt limit was reached across resends")
+	}
+}
+
+func TestOTPFailuresResetOnSuccess(t *testing.T) {
+	newMemoryAttempts(t)
+	s := &authService{repo: &otpRepo{}, mailer: discardMailer{}}
+	userID := uuid.New()
+
+	code, _, err := s.SendOTP(userID, "user@example.com", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxOTPAttempts-1; i++ {
+		s.FindValidOTP(userID, "wrong", fmt.Sprintf("10.0.0.%d", i))
+	}
+	if _, err := s.FindValidOTP(userID, code, "10.0.1.1"); err != nil {
+		t.Fatalf("valid code rejected: %v", err)
+	}
+
+	code, _, _ = s.SendOTP(userID, "user@example.com", "en")
+	s.FindValidOTP(userID, "wrong", "10.0.1.1")
+	if _, err := s.FindValidOTP(userID, code, "10.0.1.1"); err != nil {
+		t.Fatalf("OTP counter was not reset after success: %v", err)
+	}
+}
+
+// recoveryRepo хранит резервные коды в памяти
+type recoveryRepo struct {
+	repository.AuthRepository
+	codes []models.RecoveryCode
+}
+
+func (r *recoveryRepo) FindUnusedRecoveryCode(userID uuid.UUID, codeHash string) (*models.RecoveryCode, error) {
+	for i := range r.codes {
+		if r.codes[i].UserID == userID && r.codes[i].CodeHash == codeHash && r.codes[i].UsedAt == nil {
+			return &r.codes[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *recoveryRepo) MarkRecoveryCodeAsUsed(id uuid.UUID, ip string) error {
+	now := time.Now()
+	for i := range r.codes {
+		if r.codes[i].ID == id {
+			r.codes[i].UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func TestRecoveryCodeLockout(t *testing.T) {
+	newMemoryAttempts(t)
+	userID := uuid.New()
+	const code = "abcde-12345"
+	repo := &recoveryRepo{codes: []models.RecoveryCode{{ID: uuid.New(), UserID: userID, CodeHash: utils.HashRecoveryCode(code)}}}
+	s := &authService{repo: repo}
+
+	for i := 0; i < maxFailedAttempts-1; i++ {
+		if err := s.UseRecoveryCode(userID, "wrong", "10.0.0.1"); err == nil || isLocked(err) {
+			t.Fatalf("attempt %d: err = %v, want invalid code", i+1, err)
+		}
+	}
+	if err := s.UseRecoveryCode(userID, "wrong", "10.0.0.1"); !isLocked(err) {
+		t.Fatalf("attempt %d: err = %v, want LockedError", maxFailedAttempts, err)
+	}
+	// Во время блокировки не принимается даже верный код
+	if err := s.UseRecoveryCode(userID, code, "10.0.0.1"); !isLocked(err) {
+		t.Fatalf("valid code during lock: err = %v, want LockedError", err)
+	}
+	if repo.codes[0].UsedAt != nil {
+		t.Fatal("recovery code spent while locked")
+	}
+	// Ошибки общие с TOTP и OTP: блокировка по тому же scope
+	if err := checkLock("verify:"+userID.String(), "10.0.0.1"); !isLocked(err) {
+		t.Fatal("verify scope is not locked")
+	}
+}
+
+// totpRepo отдаёт одного пользователя с уже зашифрованным TOTP-секретом
+type totpRepo struct {
+	repository.AuthRepository
+	user models.User
+}
+
+func (r *totpRepo) FindByID(id uuid.UUID) (*models.User, error) {
+	if id != r.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	u := r.user
+	return &u, nil
+}
+
+func (r *totpRepo) UpdateUser(user *models.User) error {
+	r.user = *user
+	return nil
+}
+
+func newTOTPUser(t *testing.T, enabled bool) *totpRepo {
+	t.Helper()
+	prev := config.Env
+	config.Env = &config.AuthConfig{TOTPEncryptionKey: []byte("test-key")}
+	t.Cleanup(func() { config.Env = prev })
+
+	secret, err := utils.EncryptSecret("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &totpRepo{user: models.User{ID: uuid.New(), TOTPSecret: secret, TOTPEnabled: enabled}}
+}
+
+func TestTOTPManagementLockout(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		call    func(s *authService, userID uuid.UUID, ip string) error
+	}{
+		{"confirm", false, func(s *authService, userID uuid.UUID, ip string) error {
+			_, err := s.ConfirmTOTP(userID, "wrong", ip)
+			return err
+		}},
+		{"disable", true, func(s *authService, userID uuid.UUID, ip string) error {
+			return s.DisableTOTP(userID, "wrong", ip)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newMemoryAttempts(t)
+			repo := newTOTPUser(t, tt.enabled)
+			s := &authService{repo: repo}
+			userID := repo.user.ID
+
+			for i := 0; i < maxFailedAttempts-1; i++ {
+				if err := tt.call(s, userID, "10.0.0.1"); err == nil || isLocked(err) {
+					t.Fatalf("attempt %d: err = %v, want invalid code", i+1, err)
+				}
+			}
+			if err := tt.call(s, userID, "10.0.0.1"); !isLocked(err) {
+				t.Fatalf("attempt %d: err = %v, want LockedError", maxFailedAttempts, err)
+			}
+			// Блокировка общая со входом по 2FA
+			if err := checkLock("verify:"+userID.String(), "10.0.0.1"); !isLocked(err) {
+				t.Fatal("verify scope is not locked")
+			}
+		})
+	}
+}
@@ -4,52 +4,142 @@ import (
 	"auth/config"
 	"auth/internal/models"
 	"auth/internal/repository"
+	"auth/pkg/hasher"
+	"auth/pkg/jwk"
+	"auth/pkg/mailer"
+	"auth/pkg/oauth"
+	"auth/pkg/password"
+	"auth/pkg/rabbitmq"
 	"auth/pkg/redis"
 	"auth/pkg/utils"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/pquerna/otp"
 	"gorm.io/gorm"
 )
 
 type AuthService interface {
 	Register(email, password string) (uuid.UUID, error)
-	Login(email, password string) (uuid.UUID, error)
+	Login(email, password, ip string) (uuid.UUID, error)
 	GetUserByID(id uuid.UUID) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	DeleteUserByID(id uuid.UUID) error
 	UpdateUser(user *models.User) error
 	UpdatePassword(userID uuid.UUID, newPassword string) error
+	ValidateNewPassword(userID uuid.UUID, newPassword string) error
+	ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error
+	CheckPassword(userID uuid.UUID, password string) error
+	RequestEmailChange(userID uuid.UUID, newEmail, password, lang string) error
+	ConfirmEmailChange(userID uuid.UUID, oldCode, newCode, ip string) (string, error)
 	ScheduleDeletion(userID uuid.UUID, deletionTime time.Time) error
 	CancelDeletion(userID uuid.UUID) error
+	PurgeExpiredDeletions() (int, error)
+	PurgeExpiredRefreshTokens() (int64, error)
 
 	GenerateTokens(userID uuid.UUID, ip, userAgent, device string) (string, string, error)
-	Refresh(refreshToken string) (string, string, error)
+	Refresh(refreshToken, ip string) (string, string, error)
 	BlacklistAccessToken(c *gin.Context, accessToken string) error
+	ConsumeTempToken(tempToken, action string) (uuid.UUID, error)
+	PeekTempToken(tempToken, action string) (uuid.UUID, error)
 	RevokeRefreshToken(refreshToken string) error
 	RevokeAllRefreshTokens(userID uuid.UUID) error
+	RevokeOtherRefreshTokens(userID uuid.UUID, currentRefreshToken string) error
+	RevokeSession(userID, sessionID uuid.UUID) error
 	ListActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error)
 
+	TrustDevice(userID uuid.UUID, ip, userAgent string) (string, time.Time, error)
+	IsTrustedDevice(userID uuid.UUID, token, ip string) bool
+	TrustedDeviceID(userID uuid.UUID, token string) (uuid.UUID, error)
+	ListTrustedDevices(userID uuid.UUID) ([]models.TrustedDevice, error)
+	RevokeTrustedDevice(userID, deviceID uuid.UUID) error
+	RevokeAllTrustedDevices(userID uuid.UUID) error
+
 	MarkOTPAsUsed(id uuid.UUID) error
-	SendOTP(userID uuid.UUID, email string) (string, time.Time, error)
-	FindValidOTP(userID uuid.UUID, code string) (*models.OTPCode, error)
+	SendOTP(userID uuid.UUID, email, lang string) (string, time.Time, error)
+	SendMagicLink(email, lang string) error
+	FindValidOTP(userID uuid.UUID, code, ip string) (*models.OTPCode, error)
+
+	EnrollTOTP(userID uuid.UUID) (*otp.Key, error)
+	ConfirmTOTP(userID uuid.UUID, code, ip string) ([]string, error)
+	DisableTOTP(userID uuid.UUID, code, ip string) error
+	VerifyTOTP(userID uuid.UUID, code, ip string) error
+
+	RegenerateRecoveryCodes(userID uuid.UUID, totpCode, ip string) ([]string, error)
+	UseRecoveryCode(userID uuid.UUID, code, ip string) error
+
+	BeginPasskeyRegistration(userID uuid.UUID) (*protocol.CredentialCreation, error)
+	FinishPasskeyRegistration(userID uuid.UUID, name string, body []byte) (*models.Passkey, error)
+	BeginPasskeyLogin() (*protocol.CredentialAssertion, string, error)
+	FinishPasskeyLogin(sessionID string, body []byte) (*models.User, error)
+	ListPasskeys(userID uuid.UUID) ([]models.Passkey, error)
+	DeletePasskey(userID, passkeyID uuid.UUID) error
+
+	StartOAuth(provider string, linkUserID uuid.UUID) (string, string, error)
+	CompleteOAuth(provider, state, code string) (*OAuthResult, error)
+	ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error)
+	UnlinkIdentity(userID uuid.UUID, provider string) error
+
+	RecordSecurityEvent(userID uuid.UUID, eventType, ip, userAgent, details string)
+	RecordLogin(userID uuid.UUID, ip, userAgent, lang string)
+	ListSecurityEvents(userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, int64, error)
+
+	CreateBot(ownerID uuid.UUID, name string) (*models.User, error)
+	ListBots(ownerID uuid.UUID) ([]models.User, error)
+	DeleteBot(ownerID, botID uuid.UUID) error
+	CreateAPIToken(creatorID uuid.UUID, botID *uuid.UUID, name string, scopes []string, ttl time.Duration) (string, *models.APIToken, error)
+	ListAPITokens(creatorID uuid.UUID) ([]models.APIToken, error)
+	RevokeAPIToken(creatorID, tokenID uuid.UUID) error
+	SyncAPITokens() error
+
+	AuthenticateClient(clientID, clientSecret string) (*config.ServiceClient, error)
+	IssueServiceToken(client *config.ServiceClient) (string, time.Duration, error)
+	Introspect(token string) (*Introspection, error)
+
+	EnsureAdmins() error
+	SyncSuspensions() error
+	SearchUsers(email string, limit, offset int) ([]models.User, int64, error)
+	ListAuditEntries(targetID *uuid.UUID, limit, offset int) ([]models.AuditEntry, int64, error)
+	AdminVerifyUser(actorID, targetID uuid.UUID, ip string) error
+	AdminSuspendUser(actorID, targetID uuid.UUID, reason string, until *time.Time, ip string) error
+	AdminUnsuspendUser(actorID, targetID uuid.UUID, ip string) error
+	AdminForceLogout(actorID, targetID uuid.UUID, ip string) error
+	AdminScheduleDeletion(actorID, targetID uuid.UUID, ip string) (time.Time, error)
+	AdminCancelDeletion(actorID, targetID uuid.UUID, ip string) error
+	AdminSetRole(actorID, targetID uuid.UUID, role, ip string) error
 }
 type authService struct {
-	repo repository.AuthRepository
+	repo      repository.AuthRepository
+	mailer    mailer.Mailer
+	webauthn  *webauthn.WebAuthn
+	providers map[string]*oauth.Provider
+	passwords *password.Checker
+	hasher    hasher.Hasher
 }
 
-func NewAuthService(repo repository.AuthRepository) AuthService {
-	return &authService{repo: repo}
+func NewAuthService(repo repository.AuthRepository, mailer mailer.Mailer, wa *webauthn.WebAuthn, providers map[string]*oauth.Provider, passwords *password.Checker, hasher hasher.Hasher) AuthService {
+	return &authService{repo: repo, mailer: mailer, webauthn: wa, providers: providers, passwords: passwords, hasher: hasher}
 }
 
 // ! User
 
 func (s *authService) Register(email, password string) (uuid.UUID, error) {
+	email = utils.NormalizeEmail(email)
+	if err := s.checkNewPassword(nil, email, password); err != nil {
+		return uuid.Nil, err
+	}
+
 	res, err := s.repo.FindByEmail(email)
 	if err == nil { // Такой пользователь есть
 		if !res.IsVerified {
@@ -62,12 +152,12 @@ func (s *authService) Register(email, password string) (uuid.UUID, error) {
 		}
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := s.hasher.Hash(password)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	user := models.User{Email: email, Password: string(hash)}
+	user := models.User{Email: email, Password: hash}
 	if err := s.repo.CreateUser(&user); err != nil {
 		return uuid.Nil, err
 	}
@@ -75,11 +165,28 @@ func (s *authService) Register(email, password string) (uuid.UUID, error) {
 	return user.ID, nil
 }
 
-func (s *authService) Login(email, password string) (uuid.UUID, error) {
+func (s *authService) Login(email, password, ip string) (uuid.UUID, error) {
+	email = utils.NormalizeEmail(email)
+	// Счётчик ведётся по email и IP, даже если такого аккаунта нет
+	scope := "login:" + email
+	if err := checkLock(scope, ip); err != nil {
+		return uuid.Nil, err
+	}
+
 	user, err := s.repo.FindByEmail(email)
-	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil || !user.IsVerified {
+	if err != nil || user.Type == models.UserTypeBot || !s.passwordMatches(user.Password, password) || !user.IsVerified {
+		var locked *LockedError
+		if errors.As(registerFailure(scope, ip), &locked) {
+			return uuid.Nil, locked
+		}
 		return uuid.Nil, errors.New("invalid credentials")
 	}
+
+	resetFailures(scope, ip)
+	if err := suspendedError(user); err != nil {
+		return uuid.Nil, err
+	}
+	s.rehashIfNeeded(user, password)
 	return user.ID, err
 }
 
@@ -88,7 +195,7 @@ func (s *authService) GetUserByID(id uuid.UUID) (*models.User, error) {
 }
 
 func (s *authService) GetUserByEmail(email string) (*models.User, error) {
-	return s.repo.FindByEmail(email)
+	return s.repo.FindByEmail(utils.NormalizeEmail(email))
 }
 
 func (s *authService) DeleteUserByID(id uuid.UUID) error {
@@ -105,13 +212,148 @@ func (s *authService) UpdatePassword(userID uuid.UUID, newPassword string) error
 		return err
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(newPassword)) == nil {
-		return errors.New("пароли не должны совпадать")
+	if err := s.checkNewPassword(user, user.Email, newPassword); err != nil {
+		return err
+	}
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	user.Password = string(hash)
 
-	return s.repo.UpdateUser(user)
+	previousHash := user.Password
+	user.Password = hash
+	// В истории хранится HistorySize-1 прежних паролей: вместе с текущим это последние HistorySize
+	if err := s.repo.UpdatePassword(user, previousHash, s.passwords.Policy.HistorySize-1); err != nil {
+		return err
+	}
+	// Устройства, которым доверяли со старым паролем, снова проходят OTP
+	if err := s.repo.DeleteTrustedDevices(userID); err != nil {
+		log.Printf("[Trusted] Failed to revoke trusted devices of %s: %v", userID, err)
+	}
+	return nil
+}
+
+func (s *authService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !s.passwordMatches(user.Password, currentPassword) {
+		return errors.New("invalid current password")
+	}
+	return s.UpdatePassword(userID, newPassword)
+}
+
+// CheckPassword — повторное подтверждение пароля перед опасными действиями
+func (s *authService) CheckPassword(userID uuid.UUID, password string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil || !s.passwordMatches(user.Password, password) {
+		return errors.New("invalid password")
+	}
+	return nil
+}
+
+// ! Смена email
+
+const emailChangeTTL = 15 * time.Minute
+
+type pendingEmailChange struct {
+	NewEmail    string `json:"new_email"`
+	OldCodeHash string `json:"old_code_hash"`
+	NewCodeHash string `json:"new_code_hash"`
+}
+
+// RequestEmailChange отправляет коды на текущую и новую почту; смена применяется только после подтверждения обоих
+func (s *authService) RequestEmailChange(userID uuid.UUID, newEmail, password, lang string) error {
+	newEmail = utils.NormalizeEmail(newEmail)
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !s.passwordMatches(user.Password, password) {
+		return errors.New("invalid password")
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return errors.New("new email matches the current one")
+	}
+	if _, err := s.repo.FindByEmail(newEmail); err == nil {
+		return errors.New("email already exists")
+	}
+
+	oldCode, newCode := utils.GenerateOTP(), utils.GenerateOTP()
+	pending, _ := json.Marshal(pendingEmailChange{
+		NewEmail:    newEmail,
+		OldCodeHash: utils.HashToken(oldCode),
+		NewCodeHash: utils.HashToken(newCode),
+	})
+	key := "auth:email-change:" + userID.String()
+	if err := redis.AuthRedis.Set(context.Background(), key, pending, emailChangeTTL).Err(); err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(emailChangeTTL)
+	for _, m := range []struct{ to, code string }{{user.Email, oldCode}, {newEmail, newCode}} {
+		msg, err := mailer.OTPMessage(m.to, lang, m.code, expires)
+		if err != nil {
+			return err
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			return fmt.Errorf("failed to deliver OTP: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *authService) ConfirmEmailChange(userID uuid.UUID, oldCode, newCode, ip string) (string, error) {
+	scope := "verify:" + userID.String()
+	if err := checkLock(scope, ip); err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	key := "auth:email-change:" + userID.String()
+	raw, err := redis.AuthRedis.Get(ctx, key).Bytes()
+	if err != nil {
+		return "", errors.New("no pending email change")
+	}
+	var pending pendingEmailChange
+	if err := json.Unmarshal(raw, &pending); err != nil {
+		return "", err
+	}
+
+	if utils.HashToken(oldCode) != pending.OldCodeHash || utils.HashToken(newCode) != pending.NewCodeHash {
+		var locked *LockedError
+		if errors.As(registerFailure(scope, ip), &locked) {
+			return "", locked
+		}
+		return "", errors.New("invalid or expired OTP")
+	}
+	resetFailures(scope, ip)
+
+	// Адрес мог быть занят, пока шло подтверждение
+	if _, err := s.repo.FindByEmail(pending.NewEmail); err == nil {
+		return "", errors.New("email already exists")
+	}
+
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+	oldEmail := user.Email
+	user.Email = pending.NewEmail
+	if err := s.repo.UpdateUser(user); err != nil {
+		return "", err
+	}
+	redis.AuthRedis.Del(ctx, key)
+
+	err = rabbitmq.PublishUserEvent(user.ID, "user_email_changed", map[string]interface{}{
+		"old_email": oldEmail,
+		"new_email": user.Email,
+	})
+	if err != nil {
+		log.Printf("[EmailChange] Failed to publish user_email_changed event: %v", err)
+	}
+	return user.Email, nil
 }
 
 func (s *authService) ScheduleDeletion(userID uuid.UUID, deletionTime time.Time) error {
@@ -122,46 +364,185 @@ func (s *authService) CancelDeletion(userID uuid.UUID) error {
 	return s.repo.CancelDeletion(userID)
 }
 
+// PurgeExpiredRefreshTokens удаляет истёкшие refresh-токены, в том числе ротированные: предъявить их уже нельзя,
+// поэтому и для обнаружения повторного использования они не нужны
+func (s *authService) PurgeExpiredRefreshTokens() (int64, error) {
+	return s.repo.DeleteExpiredRefreshTokens(time.Now())
+}
+
+// PurgeExpiredDeletions окончательно удаляет аккаунты, у которых истёк срок ToBeDeletedAt
+func (s *authService) PurgeExpiredDeletions() (int, error) {
+	users, err := s.repo.FindExpiredDeletions(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, user := range users {
+		// Боты удаляются вместе с владельцем
+		bots, err := s.repo.ListBots(user.ID)
+		if err != nil {
+			log.Printf("[Deletion] Failed to list bots of %s: %v", user.ID, err)
+			continue
+		}
+		for _, bot := range bots {
+			if err := s.purgeUser(bot.ID); err != nil {
+				log.Printf("[Deletion] Failed to delete bot %s: %v", bot.ID, err)
+			}
+		}
+
+		if err := s.purgeUser(user.ID); err != nil {
+			log.Printf("[Deletion] Failed to delete user %s: %v", user.ID, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// purgeUser окончательно удаляет аккаунт и отзывает все его токены
+func (s *authService) purgeUser(userID uuid.UUID) error {
+	if err := s.repo.RevokeAll(userID); err != nil {
+		return fmt.Errorf("revoke refresh tokens: %w", err)
+	}
+	if err := s.revokeUserAPITokens(userID); err != nil {
+		return fmt.Errorf("revoke API tokens: %w", err)
+	}
+	if err := s.repo.DeleteAllOTPs(userID); err != nil {
+		return fmt.Errorf("delete OTPs: %w", err)
+	}
+	// Жёсткое удаление: освобождает email и каскадно удаляет связанные записи
+	if err := s.repo.DeleteUser(userID, false); err != nil {
+		return err
+	}
+
+	if err := rabbitmq.PublishUserEvent(userID, "user_deleted", nil); err != nil {
+		log.Printf("[Deletion] Failed to publish user_deleted event for %s: %v", userID, err)
+	}
+	return nil
+}
+
 // ! Token
 
 func (s *authService) GenerateTokens(userID uuid.UUID, ip, userAgent, device string) (string, string, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if err := suspendedError(user); err != nil {
+		return "", "", err
+	}
+
 	now := time.Now()
+	return s.issueTokens(user, &models.RefreshToken{
+		UserID:           userID,
+		FamilyID:         uuid.New(), // новая сессия — новое семейство
+		IP:               ip,
+		UserAgent:        userAgent,
+		Device:           device,
+		SessionCreatedAt: now,
+		LastUsedAt:       now,
+		LastIP:           ip,
+	})
+}
+
+// issueTokens подписывает access-токен и сохраняет refresh-токен с заполненными полями rt
+func (s *authService) issueTokens(user *models.User, rt *models.RefreshToken) (string, string, error) {
+	now := time.Now()
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
 	// Access token
-	access := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.MapClaims{
-			"id":  userID,
-			"exp": now.Add(config.Env.AccessTokenDuration).Unix(),
-			"jti": uuid.New().String(), // id токена
-		},
-	)
-	accessToken, err := access.SignedString(config.Env.JWTSecret)
+	accessToken, err := jwk.Sign(jwt.MapClaims{
+		"id":   rt.UserID,
+		"typ":  "access",
+		"sid":  rt.FamilyID, // сессия, из которой выдан токен
+		"role": role,
+		"exp":  now.Add(config.Env.AccessTokenDuration).Unix(),
+		"jti":  uuid.New().String(), // id токена
+	})
 	if err != nil {
 		return "", "", err
 	}
 
 	// Refresh token
-	refreshToken := uuid.New().String()
-	expiresAt := now.Add(config.Env.RefreshTokenDuration)
+	rt.Token = uuid.New().String()
+	rt.ExpiresAt = now.Add(config.Env.RefreshTokenDuration)
 
-	if err := s.repo.CreateRefreshToken(refreshToken, userID, expiresAt, ip, userAgent, device); err != nil {
+	if err := s.repo.CreateRefreshToken(rt); err != nil {
 		return "", "", err
 	}
 
-	return accessToken, refreshToken, nil
+	return accessToken, rt.Token, nil
 }
 
-func (s *authService) Refresh(refreshToken string) (string, string, error) {
-	rt, err := s.repo.FindValidByToken(refreshToken)
+func (s *authService) Refresh(refreshToken, ip string) (string, string, error) {
+	rt, err := s.repo.FindByToken(refreshToken)
+	if err != nil {
+		return "", "", errors.New("invalid or expired token")
+	}
+
+	familyID := rt.FamilyID
+	if familyID == uuid.Nil { // токены, выданные до появления семейств
+		familyID = rt.ID
+	}
+
+	user, err := s.repo.FindByID(rt.UserID)
 	if err != nil {
 		return "", "", errors.New("invalid or expired token")
 	}
+	if err := suspendedError(user); err != nil {
+		return "", "", err
+	}
 
-	// Отзываем старый
-	err = s.repo.Revoke(refreshToken)
+	// Помечаем старый как ротированный; если это уже сделано — токен предъявлен повторно
+	rotated, err := s.repo.MarkRotated(rt.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if !rotated {
+		s.handleRefreshReuse(rt, familyID)
+		return "", "", errors.New("refresh token reuse detected, session revoked")
+	}
 
-	access, newRefresh, err := s.GenerateTokens(rt.UserID, rt.IP, rt.UserAgent, rt.Device)
-	return access, newRefresh, err
+	parentID := rt.ID
+	sessionCreatedAt := rt.SessionCreatedAt
+	if sessionCreatedAt.IsZero() {
+		sessionCreatedAt = time.Now()
+	}
+	return s.issueTokens(user, &models.RefreshToken{
+		UserID:           rt.UserID,
+		FamilyID:         familyID,
+		ParentID:         &parentID,
+		IP:               rt.IP,
+		UserAgent:        rt.UserAgent,
+		Device:           rt.Device,
+		SessionCreatedAt: sessionCreatedAt,
+		LastUsedAt:       time.Now(),
+		LastIP:           ip,
+	})
+}
+
+// handleRefreshReuse отзывает всю сессию: ротированный токен мог быть украден
+func (s *authService) handleRefreshReuse(rt *models.RefreshToken, familyID uuid.UUID) {
+	if err := s.repo.RevokeFamily(familyID); err != nil {
+		log.Printf("[Refresh] Failed to revoke token family %s: %v", familyID, err)
+	}
+	if rt.FamilyID == uuid.Nil {
+		_ = s.repo.Revoke(rt.Token)
+	}
+	markSessionRevoked(familyID)
+
+	log.Printf("[Refresh] Reuse of rotated refresh token detected for user %s (family %s)", rt.UserID, familyID)
+	err := rabbitmq.PublishUserEvent(rt.UserID, "refresh_token_reused", map[string]interface{}{
+		"family_id": familyID,
+		"ip":        rt.IP,
+		"device":    rt.Device,
+	})
+	if err != nil {
+		log.Printf("[Refresh] Failed to publish refresh_token_reused event: %v", err)
+	}
 }
 
 func (s *authService) BlacklistAccessToken(c *gin.Context, accessToken string) error {
@@ -184,12 +565,123 @@ func (s *authService) BlacklistAccessToken(c *gin.Context, accessToken string) e
 	return nil
 }
 
+// ConsumeTempToken проверяет временный токен и помечает его jti использованным до истечения срока действия
+func (s *authService) ConsumeTempToken(tempToken, action string) (uuid.UUID, error) {
+	claims, userID, err := s.parseTempToken(tempToken, action)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	expFloat, _ := claims["exp"].(float64)
+	remaining := time.Until(time.Unix(int64(expFloat), 0))
+	if remaining <= 0 {
+		return uuid.Nil, errors.New("invalid or expired token")
+	}
+
+	key := "auth:temp:used:" + jti
+	fresh, err := redis.AuthRedis.SetNX(context.Background(), key, "1", remaining).Result()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !fresh {
+		return uuid.Nil, errors.New("token has already been used")
+	}
+	return userID, nil
+}
+
+// PeekTempToken проверяет одноразовый токен, не расходуя его
+func (s *authService) PeekTempToken(tempToken, action string) (uuid.UUID, error) {
+	claims, userID, err := s.parseTempToken(tempToken, action)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	jti, _ := claims["jti"].(string)
+	used, err := redis.AuthRedis.Exists(context.Background(), "auth:temp:used:"+jti).Result()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if used > 0 {
+		return uuid.Nil, errors.New("token has already been used")
+	}
+	return userID, nil
+}
+
+func (s *authService) parseTempToken(tempToken, action string) (jwt.MapClaims, uuid.UUID, error) {
+	claims, err := utils.ValidateTempToken(tempToken)
+	if err != nil || claims["action"] != action {
+		return nil, uuid.Nil, errors.New("invalid or expired token")
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, err := uuid.Parse(fmt.Sprint(claims["id"]))
+	if jti == "" || err != nil {
+		return nil, uuid.Nil, errors.New("invalid or expired token")
+	}
+	return claims, userID, nil
+}
+
 func (s *authService) RevokeRefreshToken(refreshToken string) error {
 	return s.repo.Revoke(refreshToken)
 }
 
+// RevokeAllRefreshTokens завершает все сессии пользователя, включая уже выданные из них access-токены
 func (s *authService) RevokeAllRefreshTokens(userID uuid.UUID) error {
-	return s.repo.RevokeAll(userID)
+	sessions, err := s.repo.ListActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RevokeAll(userID); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		markSessionRevoked(session.FamilyID)
+	}
+	return nil
+}
+
+// RevokeOtherRefreshTokens завершает все сессии, кроме текущей, включая уже выданные из них access-токены
+func (s *authService) RevokeOtherRefreshTokens(userID uuid.UUID, currentRefreshToken string) error {
+	sessions, err := s.repo.ListActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+	var currentFamily uuid.UUID
+	if current, err := s.repo.FindByToken(currentRefreshToken); err == nil && current.UserID == userID {
+		currentFamily = current.FamilyID
+	}
+
+	if err := s.repo.RevokeAllExcept(userID, currentRefreshToken); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.FamilyID != currentFamily {
+			markSessionRevoked(session.FamilyID)
+		}
+	}
+	return nil
+}
+
+// RevokeSession завершает одну сессию пользователя; выданные из неё access-токены перестают приниматься
+func (s *authService) RevokeSession(userID, sessionID uuid.UUID) error {
+	rt, err := s.repo.FindActiveSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeFamily(rt.FamilyID); err != nil {
+		return err
+	}
+	markSessionRevoked(rt.FamilyID)
+	return nil
+}
+
+// markSessionRevoked хранит отметку, пока не истекут все access-токены сессии
+func markSessionRevoked(sessionID uuid.UUID) {
+	key := "auth:session:revoked:" + sessionID.String()
+	if err := redis.AuthRedis.Set(context.Background(), key, "1", config.Env.AccessTokenDuration).Err(); err != nil {
+		log.Printf("[Session] Failed to mark session %s as revoked: %v", sessionID, err)
+	}
 }
 
 func (s *authService) ListActiveSessions(userID uuid.UUID) ([]models.RefreshToken, error) {
@@ -198,7 +690,7 @@ func (s *authService) ListActiveSessions(userID uuid.UUID) ([]models.RefreshToke
 
 // ! OTP
 
-func (s *authService) SendOTP(userID uuid.UUID, email string) (string, time.Time, error) {
+func (s *authService) SendOTP(userID uuid.UUID, email, lang string) (string, time.Time, error) {
 	code := utils.GenerateOTP()
 	expires := time.Now().Add(10 * time.Minute)
 
@@ -218,15 +710,240 @@ func (s *authService) SendOTP(userID uuid.UUID, email string) (string, time.Time
 		return "", time.Time{}, err
 	}
 
-	fmt.Printf("\n*| email: %s\n*| otp code: %s\n*| expires at: %s\n\n", email, otp.Code, expires.Format("15:04:05"))
+	msg, err := mailer.OTPMessage(email, lang, code, expires)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to deliver OTP: %w", err)
+	}
 
 	return code, expires, nil
 }
 
-func (s *authService) FindValidOTP(userID uuid.UUID, code string) (*models.OTPCode, error) {
-	return s.repo.FindValidOTP(userID, code)
+// SendMagicLink отправляет одноразовую ссылку для входа; для несуществующих адресов молча ничего не делает
+func (s *authService) SendMagicLink(email, lang string) error {
+	user, err := s.repo.FindByEmail(utils.NormalizeEmail(email))
+	if err != nil || !user.IsVerified || user.Type == models.UserTypeBot {
+		return nil
+	}
+
+	const ttl = 15 * time.Minute
+	token, err := utils.GenerateTempToken(user.ID, ttl, "magic_link")
+	if err != nil {
+		return err
+	}
+
+	link := config.Env.MagicLinkURL + "?token=" + url.QueryEscape(token)
+	msg, err := mailer.MagicLinkMessage(user.Email, lang, link, time.Now().Add(ttl))
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return fmt.Errorf("failed to deliver magic link: %w", err)
+	}
+	return nil
+}
+
+func (s *authService) FindValidOTP(userID uuid.UUID, code, ip string) (*models.OTPCode, error) {
+	scope := "verify:" + userID.String()
+	if err := checkLock(scope, ip); err != nil {
+		return nil, err
+	}
+
+	otp, err := s.repo.FindValidOTP(userID, code)
+	if err != nil {
+		// Слишком много ошибок — текущий код больше не принимается, нужен новый. Пока счётчик не истёк,
+		// и новый код аннулируется после первой же ошибки
+		if countOTPFailure(userID.String()) {
+			_ = s.repo.InvalidateAllActiveOTPs(userID)
+		}
+		var locked *LockedError
+		if errors.As(registerFailure(scope, ip), &locked) {
+			return nil, locked
+		}
+		return nil, err
+	}
+
+	resetFailures(scope, ip)
+	resetOTPFailures(userID.String())
+	return otp, nil
 }
 
 func (s *authService) MarkOTPAsUsed(id uuid.UUID) error {
 	return s.repo.MarkOTPAsUsed(id)
 }
+
+// ! TOTP (2FA)
+
+func (s *authService) EnrollTOTP(userID uuid.UUID) (*otp.Key, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("2FA is already enabled")
+	}
+
+	key, err := utils.GenerateTOTPKey(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptSecret(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+
+	// Секрет сохраняется сразу, но 2FA включается только после подтверждения кодом
+	user.TOTPSecret = encrypted
+	if err := s.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *authService) ConfirmTOTP(userID uuid.UUID, code, ip string) ([]string, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("2FA is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("2FA enrollment not started")
+	}
+
+	if err := s.checkTOTPWithLock(user, code, ip); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if err := s.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return s.generateRecoveryCodes(user.ID)
+}
+
+func (s *authService) DisableTOTP(userID uuid.UUID, code, ip string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("2FA is not enabled")
+	}
+
+	if err := s.checkTOTPWithLock(user, code, ip); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := s.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	return s.repo.DeleteRecoveryCodes(user.ID)
+}
+
+func (s *authService) VerifyTOTP(userID uuid.UUID, code, ip string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("2FA is not enabled")
+	}
+	return s.checkTOTPWithLock(user, code, ip)
+}
+
+// checkTOTPWithLock проверяет код с учётом блокировки: ошибки считаются в общем scope "verify:<userID>",
+// чтобы подтверждение и отключение 2FA не давали отдельной серии попыток
+func (s *authService) checkTOTPWithLock(user *models.User, code, ip string) error {
+	scope := "verify:" + user.ID.String()
+	if err := checkLock(scope, ip); err != nil {
+		return err
+	}
+
+	if err := s.checkTOTP(user, code); err != nil {
+		var locked *LockedError
+		if errors.As(registerFailure(scope, ip), &locked) {
+			return locked
+		}
+		return err
+	}
+	resetFailures(scope, ip)
+	return nil
+}
+
+func (s *authService) checkTOTP(user *models.User, code string) error {
+	secret, err := utils.DecryptSecret(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	if !utils.ValidateTOTP(code, secret) {
+		return errors.New("invalid 2FA code")
+	}
+
+	// Один и тот же код нельзя использовать повторно, пока он действителен
+	key := "auth:totp:used:" + user.ID.String() + ":" + code
+	fresh, err := redis.AuthRedis.SetNX(context.Background(), key, "1", 90*time.Second).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.New("invalid 2FA code")
+	}
+	return nil
+}
+
+// ! Recovery codes
+
+const recoveryCodesCount = 10
+
+func (s *authService) RegenerateRecoveryCodes(userID uuid.UUID, totpCode, ip string) ([]string, error) {
+	if err := s.VerifyTOTP(userID, totpCode, ip); err != nil {
+		return nil, err
+	}
+	return s.generateRecoveryCodes(userID)
+}
+
+// UseRecoveryCode проверяет и гасит резервный код; ошибки считаются вместе с ошибками TOTP и OTP
+func (s *authService) UseRecoveryCode(userID uuid.UUID, code, ip string) error {
+	scope := "verify:" + userID.String()
+	if err := checkLock(scope, ip); err != nil {
+		return err
+	}
+
+	rc, err := s.repo.FindUnusedRecoveryCode(userID, utils.HashRecoveryCode(code))
+	if err != nil {
+		var locked *LockedError
+		if errors.As(registerFailure(scope, ip), &locked) {
+			return locked
+		}
+		return errors.New("invalid recovery code")
+	}
+	if err := s.repo.MarkRecoveryCodeAsUsed(rc.ID, ip); err != nil {
+		return errors.New("invalid recovery code")
+	}
+	resetFailures(scope, ip)
+	return nil
+}
+
+// generateRecoveryCodes заменяет все прежние коды новыми; в открытом виде они возвращаются только один раз
+func (s *authService) generateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	rows := make([]models.RecoveryCode, recoveryCodesCount)
+	for i := range codes {
+		codes[i] = utils.GenerateRecoveryCode()
+		rows[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashRecoveryCode(codes[i]),
+		}
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
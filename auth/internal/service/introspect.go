@@ -0,0 +1,179 @@
+package service
+
+import (
+	"auth/config"
+	"auth/internal/models"
+	"auth/pkg/jwk"
+	"auth/pkg/redis"
+	"auth/pkg/utils"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ScopeIntrospect — право сервиса вызывать introspection
+const ScopeIntrospect = "auth:introspect"
+
+// Типы токенов в ответе introspection
+const (
+	TokenTypeAccess   = "access"
+	TokenTypeAPIToken = "api_token"
+	TokenTypeService  = "service"
+)
+
+// Introspection — сведения о предъявленном токене; для неактивного токена заполнены только Active и Revoked
+type Introspection struct {
+	Active    bool
+	Subject   string
+	Scopes    []string
+	SessionID string
+	Role      string
+	TokenType string
+	ClientID  string
+	ExpiresAt int64
+	Revoked   bool
+}
+
+// ! Client credentials
+
+// AuthenticateClient проверяет id и секрет внутреннего сервиса
+func (s *authService) AuthenticateClient(clientID, clientSecret string) (*config.ServiceClient, error) {
+	for i := range config.Env.ServiceClients {
+		client := &config.Env.ServiceClients[i]
+		if client.ID != clientID {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(client.Secret), []byte(clientSecret)) == 1 {
+			return client, nil
+		}
+		break
+	}
+	return nil, errors.New("invalid client credentials")
+}
+
+// IssueServiceToken выдаёт сервису короткоживущий токен с его разрешениями
+func (s *authService) IssueServiceToken(client *config.ServiceClient) (string, time.Duration, error) {
+	ttl := config.Env.ServiceTokenDuration
+	token, err := jwk.Sign(jwt.MapClaims{
+		"sub":   client.ID,
+		"typ":   TokenTypeService,
+		"scope": strings.Join(client.Scopes, " "),
+		"exp":   time.Now().Add(ttl).Unix(),
+		"jti":   uuid.New().String(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return token, ttl, nil
+}
+
+// ! Introspection
+
+// Introspect определяет тип токена (access, персональный, сервисный) и проверяет, не отозван ли он
+func (s *authService) Introspect(token string) (*Introspection, error) {
+	if token == "" {
+		return &Introspection{}, nil
+	}
+	if utils.IsAPIToken(token) {
+		return s.introspectAPIToken(token)
+	}
+
+	parsed, err := utils.ParseToken(token)
+	if err != nil || !parsed.Valid {
+		return &Introspection{}, nil
+	}
+	claims, _ := parsed.Claims.(jwt.MapClaims)
+	typ, _ := claims["typ"].(string)
+
+	result := &Introspection{TokenType: typ}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Unix()
+	}
+
+	ctx := context.Background()
+	switch typ {
+	case TokenTypeAccess:
+		result.Subject, _ = claims["id"].(string)
+		result.SessionID, _ = claims["sid"].(string)
+		result.Role, _ = claims["role"].(string)
+		if result.Role == "" {
+			result.Role = models.RoleUser
+		}
+
+		if jti, _ := claims["jti"].(string); jti != "" {
+			exists, err := redis.AuthRedis.Exists(ctx, "auth:blacklist:access:"+jti).Result()
+			if err != nil {
+				return nil, err
+			}
+			result.Revoked = exists > 0
+		}
+		if result.SessionID != "" && !result.Revoked {
+			exists, err := redis.AuthRedis.Exists(ctx, "auth:session:revoked:"+result.SessionID).Result()
+			if err != nil {
+				return nil, err
+			}
+			result.Revoked = exists > 0
+		}
+	case TokenTypeService:
+		result.Subject, _ = claims["sub"].(string)
+		result.ClientID = result.Subject
+		if scope, _ := claims["scope"].(string); scope != "" {
+			result.Scopes = strings.Fields(scope)
+		}
+	default: // временные токены (2FA, сброс пароля) не дают доступа к API
+		return &Introspection{}, nil
+	}
+
+	if result.Revoked {
+		return &Introspection{Revoked: true}, nil
+	}
+	if typ == TokenTypeAccess {
+		suspended, err := isUserSuspended(ctx, result.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if suspended {
+			return &Introspection{}, nil
+		}
+	}
+	result.Active = true
+	return result, nil
+}
+
+func (s *authService) introspectAPIToken(token string) (*Introspection, error) {
+	record, ttl, err := s.findAPIToken(utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		// Токен отозван, истёк или не существовал
+		return &Introspection{}, nil
+	}
+
+	// Токены заблокированного пользователя не действуют, пока блокировка не снята
+	suspended, err := isUserSuspended(context.Background(), record.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+	if suspended {
+		return &Introspection{}, nil
+	}
+
+	result := &Introspection{
+		Active:    true,
+		Subject:   record.UserID.String(),
+		Scopes:    record.Scopes,
+		TokenType: TokenTypeAPIToken,
+	}
+	if ttl > 0 {
+		result.ExpiresAt = time.Now().Add(ttl).Unix()
+	}
+
+	s.touchAPIToken(record.ID)
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/oauth"
+	"auth/pkg/redis"
+	"auth/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+type oauthState struct {
+	Provider string    `json:"provider"`
+	Verifier string    `json:"verifier"`
+	LinkUser uuid.UUID `json:"link_user,omitempty"` // не пустой — привязка к уже вошедшему пользователю
+}
+
+// OAuthResult — итог обработки callback'а провайдера
+type OAuthResult struct {
+	User    *models.User
+	Created bool // аккаунт создан при этом входе
+	Linked  bool // провайдер привязан к существующему аккаунту
+}
+
+// StartOAuth сохраняет state и PKCE verifier и возвращает ссылку на провайдера и state
+// (его нужно привязать к браузеру cookie). Если linkUserID не uuid.Nil, после callback'а
+// провайдер будет привязан к этому пользователю
+func (s *authService) StartOAuth(provider string, linkUserID uuid.UUID) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", errors.New("unknown provider")
+	}
+
+	state := oauth2.GenerateVerifier() // 32 случайных байта, подходит и для state
+	verifier := oauth2.GenerateVerifier()
+	data, _ := json.Marshal(oauthState{Provider: provider, Verifier: verifier, LinkUser: linkUserID})
+	if err := redis.AuthRedis.Set(context.Background(), "auth:oauth:state:"+state, data, oauthStateTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return p.AuthCodeURL(state, verifier), state, nil
+}
+
+func (s *authService) CompleteOAuth(provider, state, code string) (*OAuthResult, error) {
+	ctx := context.Background()
+	raw, err := redis.AuthRedis.GetDel(ctx, "auth:oauth:state:"+state).Bytes()
+	if err != nil {
+		return nil, errors.New("invalid or expired state")
+	}
+	var st oauthState
+	if err := json.Unmarshal(raw, &st); err != nil || st.Provider != provider {
+		return nil, errors.New("invalid or expired state")
+	}
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	info, err := p.Exchange(ctx, code, st.Verifier)
+	if err != nil {
+		return nil, errors.New("failed to authenticate with provider")
+	}
+	info.Email = utils.NormalizeEmail(info.Email)
+
+	identity, err := s.repo.FindIdentity(provider, info.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if st.LinkUser != uuid.Nil {
+		return s.linkIdentity(st.LinkUser, provider, info, identity)
+	}
+
+	if identity != nil {
+		user, err := s.repo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthResult{User: user}, nil
+	}
+	return s.createOAuthUser(provider, info)
+}
+
+func (s *authService) linkIdentity(userID uuid.UUID, provider string, info *oauth.UserInfo, existing *models.UserIdentity) (*OAuthResult, error) {
+	if existing != nil {
+		if existing.UserID != userID {
+			return nil, errors.New("identity is already linked to another account")
+		}
+		return nil, errors.New("provider is already linked")
+	}
+
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.CreateIdentity(&models.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  info.Subject,
+		Email:    info.Email,
+	})
+	if err != nil {
+		// Уникальный индекс (user_id, provider): у пользователя уже есть другой аккаунт этого провайдера
+		return nil, errors.New("provider is already linked")
+	}
+	return &OAuthResult{User: user, Linked: true}, nil
+}
+
+// createOAuthUser создаёт подтверждённого пользователя; почта должна быть подтверждена провайдером
+func (s *authService) createOAuthUser(provider string, info *oauth.UserInfo) (*OAuthResult, error) {
+	if info.Email == "" || !info.EmailVerified {
+		return nil, errors.New("email is not verified by provider")
+	}
+
+	// Не привязываем автоматически к существующему аккаунту: владелец должен войти и привязать провайдера сам
+	if existing, err := s.repo.FindByEmail(info.Email); err == nil {
+		if existing.IsVerified {
+			return nil, errors.New("account with this email already exists")
+		}
+		if err := s.repo.DeleteUser(existing.ID, false); err != nil {
+			return nil, errors.New("error deleting old unverified user")
+		}
+	}
+
+	// Пароль неизвестен никому; задать свой можно через восстановление пароля
+	hash, err := s.hasher.Hash(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+	user := models.User{Email: info.Email, Password: hash, IsVerified: true}
+	identity := models.UserIdentity{Provider: provider, Subject: info.Subject, Email: info.Email}
+	if err := s.repo.CreateUserWithIdentity(&user, &identity); err != nil {
+		return nil, err
+	}
+	return &OAuthResult{User: &user, Created: true}, nil
+}
+
+func (s *authService) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	return s.repo.ListIdentities(userID)
+}
+
+func (s *authService) UnlinkIdentity(userID uuid.UUID, provider string) error {
+	return s.repo.DeleteIdentity(userID, provider)
+}
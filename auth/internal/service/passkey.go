@@ -0,0 +1,266 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/redis"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const passkeySessionTTL = 5 * time.Minute
+
+// passkeyUser адаптирует models.User к интерфейсу webauthn.User
+type passkeyUser struct {
+	user     *models.User
+	passkeys []models.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+	for i, pk := range u.passkeys {
+		credentials[i] = toWebAuthnCredential(pk)
+	}
+	return credentials
+}
+
+func toWebAuthnCredential(pk models.Passkey) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if pk.Transports != "" {
+		for _, t := range strings.Split(pk.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+	return webauthn.Credential{
+		ID:              pk.CredentialID,
+		PublicKey:       pk.PublicKey,
+		AttestationType: pk.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: pk.BackupEligible,
+			BackupState:    pk.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    pk.AAGUID,
+			SignCount: pk.SignCount,
+		},
+	}
+}
+
+func (s *authService) loadPasskeyUser(userID uuid.UUID) (*passkeyUser, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.repo.ListPasskeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &passkeyUser{user: user, passkeys: passkeys}, nil
+}
+
+// ! Регистрация ключа
+
+func (s *authService) BeginPasskeyRegistration(userID uuid.UUID) (*protocol.CredentialCreation, error) {
+	pu, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ключ должен быть обнаруживаемым (вход без email) и подтверждать пользователя (PIN/биометрия)
+	creation, session, err := s.webauthn.BeginRegistration(pu,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		}),
+		webauthn.WithExclusions(webauthn.Credentials(pu.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := passkeySessions.Save("auth:passkey:register:"+userID.String(), session); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// FinishPasskeyRegistration принимает ответ authenticator'а (тело navigator.credentials.create) и сохраняет ключ
+func (s *authService) FinishPasskeyRegistration(userID uuid.UUID, name string, body []byte) (*models.Passkey, error) {
+	session, err := passkeySessions.Take("auth:passkey:register:" + userID.String())
+	if err != nil {
+		return nil, err
+	}
+	pu, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return nil, errors.New("invalid passkey response")
+	}
+	credential, err := s.webauthn.CreateCredential(pu, *session, parsed)
+	if err != nil {
+		return nil, errors.New("invalid passkey response")
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+	if name == "" {
+		name = "Passkey"
+	}
+
+	pk := models.Passkey{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := s.repo.CreatePasskey(&pk); err != nil {
+		return nil, err
+	}
+	return &pk, nil
+}
+
+// ! Вход по ключу
+
+// BeginPasskeyLogin начинает вход без указания email; возвращает параметры для navigator.credentials.get и id церемонии
+func (s *authService) BeginPasskeyLogin() (*protocol.CredentialAssertion, string, error) {
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionID := uuid.New().String()
+	if err := passkeySessions.Save("auth:passkey:login:"+sessionID, session); err != nil {
+		return nil, "", err
+	}
+	return assertion, sessionID, nil
+}
+
+// FinishPasskeyLogin проверяет подпись ключа и возвращает владельца; email-OTP при этом не требуется
+func (s *authService) FinishPasskeyLogin(sessionID string, body []byte) (*models.User, error) {
+	session, err := passkeySessions.Take("auth:passkey:login:" + sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return nil, errors.New("invalid passkey response")
+	}
+
+	var stored *models.Passkey
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		pk, err := s.repo.FindPasskeyByCredentialID(rawID)
+		if err != nil {
+			return nil, err
+		}
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil || userID != pk.UserID {
+			return nil, errors.New("passkey does not belong to user")
+		}
+		pu, err := s.loadPasskeyUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		stored = pk
+		return pu, nil
+	}
+
+	wu, credential, err := s.webauthn.ValidatePasskeyLogin(handler, *session, parsed)
+	if err != nil {
+		return nil, errors.New("invalid passkey response")
+	}
+	// Счётчик подписей не вырос — возможно, ключ скопирован
+	if credential.Authenticator.CloneWarning {
+		log.Printf("[Passkey] Sign counter regression for passkey %s of user %s", stored.ID, stored.UserID)
+		return nil, errors.New("invalid passkey response")
+	}
+
+	if err := s.repo.UpdatePasskeyUsage(stored.ID, credential.Authenticator.SignCount, credential.Flags.BackupState); err != nil {
+		return nil, err
+	}
+
+	user := wu.(*passkeyUser).user
+	if !user.IsVerified {
+		return nil, errors.New("invalid passkey response")
+	}
+	return user, nil
+}
+
+// ! Управление ключами
+
+func (s *authService) ListPasskeys(userID uuid.UUID) ([]models.Passkey, error) {
+	return s.repo.ListPasskeys(userID)
+}
+
+func (s *authService) DeletePasskey(userID, passkeyID uuid.UUID) error {
+	return s.repo.DeletePasskey(userID, passkeyID)
+}
+
+// ! Хранение состояния церемонии
+
+// passkeySessionStore хранит состояние церемонии между begin и finish
+type passkeySessionStore interface {
+	Save(key string, session *webauthn.SessionData) error
+	// Take достаёт и сразу удаляет состояние: каждый challenge одноразовый
+	Take(key string) (*webauthn.SessionData, error)
+}
+
+// passkeySessions — общий для всех реплик Redis; в тестах подменяется хранилищем в памяти
+var passkeySessions passkeySessionStore = redisPasskeySessions{}
+
+type redisPasskeySessions struct{}
+
+func (redisPasskeySessions) Save(key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return redis.AuthRedis.Set(context.Background(), key, data, passkeySessionTTL).Err()
+}
+
+func (redisPasskeySessions) Take(key string) (*webauthn.SessionData, error) {
+	raw, err := redis.AuthRedis.GetDel(context.Background(), key).Bytes()
+	if err != nil {
+		return nil, errors.New("passkey ceremony expired or not started")
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
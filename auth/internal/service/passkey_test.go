@@ -0,0 +1,344 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/internal/repository"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:5173"
+)
+
+// memoryPasskeySessions — хранилище церемоний в памяти вместо Redis
+type memoryPasskeySessions map[string]*webauthn.SessionData
+
+func (m memoryPasskeySessions) Save(key string, session *webauthn.SessionData) error {
+	m[key] = session
+	return nil
+}
+
+func (m memoryPasskeySessions) Take(key string) (*webauthn.SessionData, error) {
+	session, ok := m[key]
+	if !ok {
+		return nil, errors.New("passkey ceremony expired or not started")
+	}
+	delete(m, key)
+	return session, nil
+}
+
+// passkeyRepo реализует только то, что нужно церемониям; остальные методы не вызываются
+type passkeyRepo struct {
+	repository.AuthRepository
+	user     *models.User
+	passkeys []models.Passkey
+}
+
+func (r *passkeyRepo) FindByID(id uuid.UUID) (*models.User, error) {
+	if id != r.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.user, nil
+}
+
+func (r *passkeyRepo) ListPasskeys(userID uuid.UUID) ([]models.Passkey, error) {
+	return r.passkeys, nil
+}
+
+func (r *passkeyRepo) CreatePasskey(pk *models.Passkey) error {
+	pk.ID = uuid.New()
+	r.passkeys = append(r.passkeys, *pk)
+	return nil
+}
+
+func (r *passkeyRepo) FindPasskeyByCredentialID(credentialID []byte) (*models.Passkey, error) {
+	for i := range r.passkeys {
+		if string(r.passkeys[i].CredentialID) == string(credentialID) {
+			pk := r.passkeys[i]
+			return &pk, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *passkeyRepo) UpdatePasskeyUsage(id uuid.UUID, signCount uint32, backupState bool) error {
+	for i := range r.passkeys {
+		if r.passkeys[i].ID == id {
+			r.passkeys[i].SignCount = signCount
+			r.passkeys[i].BackupState = backupState
+		}
+	}
+	return nil
+}
+
+// softAuthenticator — программный authenticator: ключ P-256, аттестация "none", UP и UV всегда выставлены
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+func (a *softAuthenticator) authData(flags protocol.AuthenticatorFlags, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], byte(flags))
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, typ protocol.CeremonyType, challenge protocol.URLEncodedBase64, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      string(typ),
+		"challenge": challenge.String(),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// create отвечает на navigator.credentials.create
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation, origin string) []byte {
+	t.Helper()
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // AAGUID из нулей
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(flags, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]string{
+		"clientDataJSON":    b64(clientData(t, protocol.CreateCeremony, creation.Response.Challenge, origin)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get отвечает на navigator.credentials.get с указанным счётчиком подписей
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion, origin string, signCount uint32) []byte {
+	t.Helper()
+	a.signCount = signCount
+	authData := a.authData(protocol.FlagUserPresent|protocol.FlagUserVerified, nil)
+	client := clientData(t, protocol.AssertCeremony, assertion.Response.Challenge, origin)
+
+	clientHash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]string{
+		"clientDataJSON":    b64(client),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func credentialJSON(t *testing.T, id []byte, response map[string]string) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       b64(id),
+		"rawId":    b64(id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func newPasskeyTestService(t *testing.T) (*authService, *passkeyRepo) {
+	t.Helper()
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "LiveChat",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := passkeySessions
+	passkeySessions = memoryPasskeySessions{}
+	t.Cleanup(func() { passkeySessions = prev })
+
+	repo := &passkeyRepo{user: &models.User{ID: uuid.New(), Email: "user@example.com", IsVerified: true}}
+	return &authService{repo: repo, webauthn: wa}, repo
+}
+
+func registerPasskey(t *testing.T, s *authService, a *softAuthenticator, userID uuid.UUID, origin string) (*models.Passkey, error) {
+	t.Helper()
+	creation, err := s.BeginPasskeyRegistration(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.FinishPasskeyRegistration(userID, "", a.create(t, creation, origin))
+}
+
+func loginPasskey(t *testing.T, s *authService, a *softAuthenticator, origin string, signCount uint32) (*models.User, error) {
+	t.Helper()
+	assertion, sessionID, err := s.BeginPasskeyLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.FinishPasskeyLogin(sessionID, a.get(t, assertion, origin, signCount))
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	s, repo := newPasskeyTestService(t)
+	a := newSoftAuthenticator(t)
+
+	pk, err := registerPasskey(t, s, a, repo.user.ID, testOrigin)
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	if pk.Name != "Passkey" || string(pk.CredentialID) != string(a.credentialID) {
+		t.Fatalf("unexpected passkey: %+v", pk)
+	}
+
+	user, err := loginPasskey(t, s, a, testOrigin, 1)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if user.ID != repo.user.ID {
+		t.Fatalf("login returned user %s, want %s", user.ID, repo.user.ID)
+	}
+	if repo.passkeys[0].SignCount != 1 {
+		t.Fatalf("sign count = %d, want 1", repo.passkeys[0].SignCount)
+	}
+}
+
+func TestPasskeyLoginSignCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		signCount uint32
+		wantErr   bool
+	}{
+		{"increased", 6, false},
+		{"same", 5, true},
+		{"regressed", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newPasskeyTestService(t)
+			a := newSoftAuthenticator(t)
+			if _, err := registerPasskey(t, s, a, repo.user.ID, testOrigin); err != nil {
+				t.Fatalf("registration failed: %v", err)
+			}
+			if _, err := loginPasskey(t, s, a, testOrigin, 5); err != nil {
+				t.Fatalf("first login failed: %v", err)
+			}
+
+			_, err := loginPasskey(t, s, a, testOrigin, tt.signCount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && repo.passkeys[0].SignCount != 5 {
+				t.Fatalf("sign count changed to %d after rejected login", repo.passkeys[0].SignCount)
+			}
+		})
+	}
+}
+
+func TestPasskeyWrongOrigin(t *testing.T) {
+	const evil = "https://evil.example"
+
+	t.Run("registration", func(t *testing.T) {
+		s, repo := newPasskeyTestService(t)
+		a := newSoftAuthenticator(t)
+		if _, err := registerPasskey(t, s, a, repo.user.ID, evil); err == nil {
+			t.Fatal("registration from a foreign origin succeeded")
+		}
+		if len(repo.passkeys) != 0 {
+			t.Fatal("passkey stored after rejected registration")
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		s, repo := newPasskeyTestService(t)
+		a := newSoftAuthenticator(t)
+		if _, err := registerPasskey(t, s, a, repo.user.ID, testOrigin); err != nil {
+			t.Fatalf("registration failed: %v", err)
+		}
+		if _, err := loginPasskey(t, s, a, evil, 1); err == nil {
+			t.Fatal("login from a foreign origin succeeded")
+		}
+	})
+}
+
+func TestPasskeyCeremonyIsSingleUse(t *testing.T) {
+	s, repo := newPasskeyTestService(t)
+	a := newSoftAuthenticator(t)
+	if _, err := registerPasskey(t, s, a, repo.user.ID, testOrigin); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+
+	assertion, sessionID, err := s.BeginPasskeyLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := a.get(t, assertion, testOrigin, 1)
+	if _, err := s.FinishPasskeyLogin(sessionID, body); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := s.FinishPasskeyLogin(sessionID, body); err == nil {
+		t.Fatal("replayed assertion accepted")
+	}
+}
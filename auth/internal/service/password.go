@@ -0,0 +1,90 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/password"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// PasswordPolicyError — новый пароль не прошёл проверку; тексты нарушений локализует обработчик
+type PasswordPolicyError struct {
+	Violations []password.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet policy"
+}
+
+// ValidateNewPassword проверяет пароль, который пользователь собирается установить, не меняя его
+func (s *authService) ValidateNewPassword(userID uuid.UUID, newPassword string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.checkNewPassword(user, user.Email, newPassword)
+}
+
+// checkNewPassword применяет политику; user == nil при регистрации, тогда история не проверяется
+func (s *authService) checkNewPassword(user *models.User, email, newPassword string) error {
+	violations := s.passwords.Check(newPassword, email)
+	if user != nil && s.isRecentPassword(user, newPassword) {
+		violations = append(violations, password.Violation{Code: password.ViolationReused, Param: s.passwords.Policy.HistorySize})
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isRecentPassword сравнивает с текущим паролем и HistorySize-1 предыдущими
+func (s *authService) isRecentPassword(user *models.User, newPassword string) bool {
+	size := s.passwords.Policy.HistorySize
+	if size <= 0 {
+		return false
+	}
+	if s.passwordMatches(user.Password, newPassword) {
+		return true
+	}
+	if size == 1 {
+		return false
+	}
+	history, err := s.repo.ListPasswordHistory(user.ID, size-1)
+	if err != nil {
+		return false
+	}
+	for _, h := range history {
+		if s.passwordMatches(h.Hash, newPassword) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordMatches проверяет пароль по хешу любого поддерживаемого алгоритма
+func (s *authService) passwordMatches(hash, password string) bool {
+	ok, err := s.hasher.Verify(hash, password)
+	if err != nil {
+		log.Printf("[Password] Failed to verify password hash: %v", err)
+	}
+	return ok
+}
+
+// rehashIfNeeded пересчитывает хеш, сделанный устаревшим алгоритмом или с более слабыми параметрами.
+// Вызывается только после успешной проверки пароля; ошибка не мешает входу — попробуем в следующий раз
+func (s *authService) rehashIfNeeded(user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Printf("[Password] Failed to rehash password for user %s: %v", user.ID, err)
+		return
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		log.Printf("[Password] Failed to save rehashed password for user %s: %v", user.ID, err)
+		return
+	}
+	user.Password = hash
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/internal/repository"
+	"auth/pkg/hasher"
+	"auth/pkg/password"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// historyRepo отдаёт историю паролей, новые сверху, с учётом limit — как настоящий репозиторий
+type historyRepo struct {
+	repository.AuthRepository
+	history []models.PasswordHistory
+}
+
+func (r *historyRepo) ListPasswordHistory(userID uuid.UUID, limit int) ([]models.PasswordHistory, error) {
+	if limit < len(r.history) {
+		return r.history[:limit], nil
+	}
+	return r.history, nil
+}
+
+func TestCheckNewPasswordHistory(t *testing.T) {
+	h, err := hasher.New(hasher.AlgorithmBcrypt, 4, hasher.Argon2Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := func(pw string) string {
+		result, err := h.Hash(pw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// Текущий пароль и три прежних, от нового к старому
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash("current1pass")}
+	repo := &historyRepo{history: []models.PasswordHistory{
+		{Hash: hash("previous1pass")},
+		{Hash: hash("previous2pass")},
+		{Hash: hash("previous3pass")},
+	}}
+
+	tests := []struct {
+		name        string
+		historySize int
+		password    string
+		wantReused  bool
+	}{
+		{"current password", 4, "current1pass", true},
+		{"latest previous", 4, "previous1pass", true},
+		{"oldest within history", 4, "previous3pass", true},
+		{"outside history", 3, "previous3pass", false},
+		{"only current checked", 1, "previous1pass", false},
+		{"history disabled", 0, "current1pass", false},
+		{"new password", 4, "brand9new", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &authService{
+				repo:      repo,
+				hasher:    h,
+				passwords: &password.Checker{Policy: password.Policy{MinLength: 8, HistorySize: tt.historySize}},
+			}
+
+			err := s.checkNewPassword(user, user.Email, tt.password)
+			var policyErr *PasswordPolicyError
+			reused := errors.As(err, &policyErr) && len(policyErr.Violations) == 1 &&
+				policyErr.Violations[0].Code == password.ViolationReused
+			if reused != tt.wantReused {
+				t.Fatalf("checkNewPassword(%q) = %v, want reused = %v", tt.password, err, tt.wantReused)
+			}
+			if !tt.wantReused && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckNewPasswordOnRegistration(t *testing.T) {
+	s := &authService{passwords: &password.Checker{Policy: password.Policy{MinLength: 8, RequireDigit: true, HistorySize: 5}}}
+
+	err := s.checkNewPassword(nil, "user@example.com", "short")
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("err = %v, want *PasswordPolicyError", err)
+	}
+	if got := codes(policyErr.Violations); len(got) != 2 || got[0] != password.ViolationTooShort || got[1] != password.ViolationNoDigit {
+		t.Fatalf("violations = %v", got)
+	}
+	if err := s.checkNewPassword(nil, "user@example.com", "river42stone"); err != nil {
+		t.Fatalf("valid password rejected: %v", err)
+	}
+}
+
+func codes(violations []password.Violation) []string {
+	result := make([]string, len(violations))
+	for i, v := range violations {
+		result[i] = v.Code
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/mailer"
+	"auth/pkg/utils"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const failedLoginEventWindow = time.Hour
+
+// RecordSecurityEvent пишет событие в журнал; ошибка записи не должна ломать основной сценарий, поэтому только логируется
+func (s *authService) RecordSecurityEvent(userID uuid.UUID, eventType, ip, userAgent, details string) {
+	// Неудачные входы может генерировать кто угодно: с одного IP за окно хранится одна запись со счётчиком
+	if eventType == models.EventLoginFailed {
+		repeated, err := s.repo.RepeatSecurityEvent(userID, eventType, ip, time.Now().Add(-failedLoginEventWindow))
+		if err != nil {
+			log.Printf("[Security] Failed to record %s event for %s: %v", eventType, userID, err)
+			return
+		}
+		if repeated {
+			return
+		}
+	}
+
+	event := models.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		IP:        ip,
+		UserAgent: userAgent,
+		Device:    utils.ParseDeviceInfo(userAgent),
+		Details:   details,
+	}
+	if err := s.repo.CreateSecurityEvent(&event); err != nil {
+		log.Printf("[Security] Failed to record %s event for %s: %v", eventType, userID, err)
+	}
+}
+
+// RecordLogin пишет успешный вход и, если такого устройства и IP раньше не было, отправляет предупреждение на почту
+func (s *authService) RecordLogin(userID uuid.UUID, ip, userAgent, lang string) {
+	device := utils.ParseDeviceInfo(userAgent)
+
+	hasLogins, err := s.repo.HasLogins(userID)
+	if err != nil {
+		log.Printf("[Security] Failed to check login history of %s: %v", userID, err)
+	}
+	seen, err := s.repo.HasLoginFrom(userID, device, ip)
+	if err != nil {
+		log.Printf("[Security] Failed to check login history of %s: %v", userID, err)
+		seen = true // без истории не шлём ложных предупреждений
+	}
+	// Первый вход (сразу после регистрации) новым устройством не считается
+	newDevice := hasLogins && !seen
+
+	event := models.SecurityEvent{
+		UserID:    userID,
+		Type:      models.EventLogin,
+		IP:        ip,
+		UserAgent: userAgent,
+		Device:    device,
+		NewDevice: newDevice,
+	}
+	if err := s.repo.CreateSecurityEvent(&event); err != nil {
+		log.Printf("[Security] Failed to record login event for %s: %v", userID, err)
+	}
+
+	if newDevice {
+		go s.sendNewDeviceAlert(userID, device, ip, lang, event.CreatedAt)
+	}
+}
+
+func (s *authService) sendNewDeviceAlert(userID uuid.UUID, device, ip, lang string, at time.Time) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		log.Printf("[Security] Failed to load user %s for new device alert: %v", userID, err)
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	msg, err := mailer.NewDeviceMessage(user.Email, lang, device, ip, at)
+	if err != nil {
+		log.Printf("[Security] Failed to render new device alert: %v", err)
+		return
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		log.Printf("[Security] Failed to deliver new device alert to %s: %v", userID, err)
+	}
+}
+
+func (s *authService) ListSecurityEvents(userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, int64, error) {
+	return s.repo.ListSecurityEvents(userID, limit, offset)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"auth/internal/models"
+	"auth/pkg/redis"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Блокировка видна всем сервисам через общий Redis: auth:suspended:<userID> хранит причину
+// и живёт до окончания блокировки (бессрочная — без TTL). Боты заблокированного пользователя
+// отмечаются так же, иначе их персональные токены продолжали бы работать
+
+// SuspendedError — аккаунт заблокирован администратором; Until == nil — бессрочно
+type SuspendedError struct {
+	Reason string
+	Until  *time.Time
+}
+
+func (e *SuspendedError) Error() string {
+	return "account suspended"
+}
+
+func suspendedError(user *models.User) error {
+	if !user.IsSuspended() {
+		return nil
+	}
+	return &SuspendedError{Reason: user.SuspensionReason, Until: user.SuspendedUntil}
+}
+
+// markUserSuspended отмечает в Redis пользователя и его ботов и возвращает ботов
+func (s *authService) markUserSuspended(user *models.User) ([]models.User, error) {
+	var ttl time.Duration
+	if user.SuspendedUntil != nil {
+		ttl = time.Until(*user.SuspendedUntil)
+		if ttl <= 0 {
+			return nil, nil
+		}
+	}
+	bots, err := s.repo.ListBots(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	pipe := redis.AuthRedis.TxPipeline()
+	pipe.Set(ctx, "auth:suspended:"+user.ID.String(), user.SuspensionReason, ttl)
+	for _, bot := range bots {
+		pipe.Set(ctx, "auth:suspended:"+bot.ID.String(), user.SuspensionReason, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return bots, err
+}
+
+func (s *authService) clearUserSuspended(userID uuid.UUID) error {
+	keys := []string{"auth:suspended:" + userID.String()}
+	bots, err := s.repo.ListBots(userID)
+	if err != nil {
+		return err
+	}
+	for _, bot := range bots {
+		keys = append(keys, "auth:suspended:"+bot.ID.String())
+	}
+	return redis.AuthRedis.Del(context.Background(), keys...).Err()
+}
+
+func isUserSuspended(ctx context.Context, userID string) (bool, error) {
+	exists, err := redis.AuthRedis.Exists(ctx, "auth:suspended:"+userID).Result()
+	return exists > 0, err
+}
+
+// SyncSuspensions восстанавливает отметки о блокировке в Redis по БД, например после перезапуска Redis
+func (s *authService) SyncSuspensions() error {
+	users, err := s.repo.ListSuspendedUsers(time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if _, err := s.markUserSuspended(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"auth/config"
+	"auth/internal/models"
+	"auth/pkg/jwk"
+	"auth/pkg/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Доверенное устройство — подписанный токен {id, typ: trusted_device, did} в долгоживущей cookie.
+// Подпись защищает от подделки, запись в БД (did) — даёт список устройств и отзыв
+
+const tokenTypeTrustedDevice = "trusted_device"
+
+const maxTrustedDevicesPerUser = 10
+
+// TrustDevice запоминает устройство после успешного входа с OTP и возвращает значение cookie
+func (s *authService) TrustDevice(userID uuid.UUID, ip, userAgent string) (string, time.Time, error) {
+	if err := s.repo.DeleteExpiredTrustedDevices(userID); err != nil {
+		log.Printf("[Trusted] Failed to delete expired devices of %s: %v", userID, err)
+	}
+	// Сверх лимита вытесняются давно не использованные
+	devices, err := s.repo.ListTrustedDevices(userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	for i := len(devices) - 1; i >= maxTrustedDevicesPerUser-1; i-- {
+		if err := s.repo.DeleteTrustedDevice(userID, devices[i].ID); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+
+	device := models.TrustedDevice{
+		UserID:    userID,
+		Device:    utils.ParseDeviceInfo(userAgent),
+		IP:        ip,
+		UserAgent: userAgent,
+		LastIP:    ip,
+		ExpiresAt: time.Now().Add(config.Env.TrustedDeviceDuration),
+	}
+	if err := s.repo.CreateTrustedDevice(&device); err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := jwk.Sign(jwt.MapClaims{
+		"id":  userID.String(),
+		"typ": tokenTypeTrustedDevice,
+		"did": device.ID.String(),
+		"exp": device.ExpiresAt.Unix(),
+		"jti": uuid.New().String(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, device.ExpiresAt, nil
+}
+
+// IsTrustedDevice проверяет cookie доверенного устройства для userID и отмечает его использование
+func (s *authService) IsTrustedDevice(userID uuid.UUID, token, ip string) bool {
+	deviceID, err := parseTrustedDeviceToken(userID, token)
+	if err != nil {
+		return false
+	}
+	device, err := s.repo.FindTrustedDevice(userID, deviceID)
+	if err != nil {
+		return false
+	}
+	if err := s.repo.UpdateTrustedDeviceUsage(device.ID, ip); err != nil {
+		log.Printf("[Trusted] Failed to update device %s usage: %v", device.ID, err)
+	}
+	return true
+}
+
+// TrustedDeviceID возвращает ID устройства из cookie (для пометки текущего в списке)
+func (s *authService) TrustedDeviceID(userID uuid.UUID, token string) (uuid.UUID, error) {
+	return parseTrustedDeviceToken(userID, token)
+}
+
+func (s *authService) ListTrustedDevices(userID uuid.UUID) ([]models.TrustedDevice, error) {
+	return s.repo.ListTrustedDevices(userID)
+}
+
+func (s *authService) RevokeTrustedDevice(userID, deviceID uuid.UUID) error {
+	return s.repo.DeleteTrustedDevice(userID, deviceID)
+}
+
+func (s *authService) RevokeAllTrustedDevices(userID uuid.UUID) error {
+	return s.repo.DeleteTrustedDevices(userID)
+}
+
+func parseTrustedDeviceToken(userID uuid.UUID, token string) (uuid.UUID, error) {
+	parsed, err := utils.ParseToken(token)
+	if err != nil || !parsed.Valid {
+		return uuid.Nil, errors.New("invalid trusted device token")
+	}
+	claims, _ := parsed.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != tokenTypeTrustedDevice {
+		return uuid.Nil, errors.New("invalid trusted device token")
+	}
+	if id, _ := claims["id"].(string); id != userID.String() {
+		return uuid.Nil, errors.New("invalid trusted device token")
+	}
+	did, _ := claims["did"].(string)
+	deviceID, err := uuid.Parse(did)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid trusted device token")
+	}
+	return deviceID, nil
+}
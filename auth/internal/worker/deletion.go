@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"auth/internal/service"
+	"context"
+	"log"
+	"time"
+)
+
+// StartDeletionWorker периодически удаляет аккаунты, срок восстановления которых истёк, и просроченные refresh-токены
+func StartDeletionWorker(ctx context.Context, sc service.AuthService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Deletion worker started (interval %s)", interval)
+	for {
+		purgeExpired(sc)
+		purgeExpiredRefreshTokens(sc)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Deletion worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeExpired(sc service.AuthService) {
+	deleted, err := sc.PurgeExpiredDeletions()
+	if err != nil {
+		log.Printf("[Deletion] Failed to find expired accounts: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[Deletion] Deleted %d account(s)", deleted)
+	}
+}
+
+func purgeExpiredRefreshTokens(sc service.AuthService) {
+	deleted, err := sc.PurgeExpiredRefreshTokens()
+	if err != nil {
+		log.Printf("[Deletion] Failed to delete expired refresh tokens: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[Deletion] Deleted %d expired refresh token(s)", deleted)
+	}
+}
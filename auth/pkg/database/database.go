@@ -31,10 +31,66 @@ func InitDB() {
 	}
 
 	// Автомиграция таблиц
-	err = db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.OTPCode{})
+	err = db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.OTPCode{}, &models.RecoveryCode{}, &models.Passkey{}, &models.UserIdentity{}, &models.SecurityEvent{}, &models.APIToken{}, &models.AuditEntry{}, &models.PasswordHistory{}, &models.TrustedDevice{})
 	if err != nil {
 		panic("failed to migrate database: " + err.Error())
 	}
+
+	if err := migrateRefreshTokenHashes(); err != nil {
+		panic("failed to migrate refresh tokens: " + err.Error())
+	}
+	if err := migrateRefreshTokenFamilies(); err != nil {
+		panic("failed to migrate refresh token families: " + err.Error())
+	}
+	if err := migrateEmailCase(); err != nil {
+		panic("failed to migrate emails: " + err.Error())
+	}
+}
+
+// migrateRefreshTokenHashes переносит старые refresh-токены из открытого вида в SHA-256 и удаляет колонку token
+func migrateRefreshTokenHashes() error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.RefreshToken{}, "token") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE refresh_tokens SET token_hash = encode(sha256(token::bytea), 'hex') WHERE token_hash IS NULL").Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.RefreshToken{}, "token")
+	})
+}
+
+// migrateRefreshTokenFamilies выдаёт старым токенам собственное семейство: его ID служит постоянным ID сессии
+func migrateRefreshTokenFamilies() error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE refresh_tokens SET last_ip = ip WHERE last_ip IS NULL OR last_ip = ''").Error
+	})
+}
+
+// migrateEmailCase приводит старые адреса к нижнему регистру. Если после этого адрес совпал бы с чужим,
+// запись не трогается и попадает в лог: такие дубликаты нужно разобрать вручную
+func migrateEmailCase() error {
+	err := db.Exec(`UPDATE users SET email = LOWER(TRIM(email))
+		WHERE email <> LOWER(TRIM(email))
+		AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id <> users.id AND LOWER(TRIM(u.email)) = LOWER(TRIM(users.email)))`).Error
+	if err != nil {
+		return err
+	}
+
+	var conflicts int64
+	if err := db.Model(&models.User{}).Where("email <> LOWER(TRIM(email))").Count(&conflicts).Error; err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		log.Printf("[DB] %d user(s) have emails that differ from another account only by case", conflicts)
+	}
+	return nil
 }
 
 func GetDB() *gorm.DB {
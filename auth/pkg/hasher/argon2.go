@@ -0,0 +1,129 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params — параметры argon2id; Memory в КиБ
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+
+	// Не параметр хеша: сколько вычислений может идти одновременно (каждое занимает Memory), 0 — без ограничения.
+	// Вход доступен без авторизации, и без ограничения поток запросов мог бы исчерпать память
+	MaxConcurrent int
+}
+
+// Длина пароля на стоимость argon2id почти не влияет, ограничение только отсекает заведомо бессмысленный ввод
+const argon2MaxPasswordLength = 1024
+
+type argon2Scheme struct {
+	params Argon2Params
+	slots  chan struct{} // nil — без ограничения
+}
+
+func newArgon2Scheme(params Argon2Params) *argon2Scheme {
+	a := &argon2Scheme{params: params}
+	if params.MaxConcurrent > 0 {
+		a.slots = make(chan struct{}, params.MaxConcurrent)
+	}
+	return a
+}
+
+// idKey считает ключ, дожидаясь свободного слота
+func (a *argon2Scheme) idKey(password, salt []byte, p Argon2Params, keyLength uint32) []byte {
+	if a.slots != nil {
+		a.slots <- struct{}{}
+		defer func() { <-a.slots }()
+	}
+	return argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, keyLength)
+}
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+func (a *argon2Scheme) validate() error {
+	p := a.params
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 || p.SaltLength < 8 || p.KeyLength < 16 || p.MaxConcurrent < 0 {
+		return errors.New("argon2id parameters are out of range")
+	}
+	return nil
+}
+
+func (a *argon2Scheme) maxPasswordLength() int {
+	return argon2MaxPasswordLength
+}
+
+func (a *argon2Scheme) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a *argon2Scheme) hash(password string) (string, error) {
+	p := a.params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := a.idKey([]byte(password), salt, p, p.KeyLength)
+
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a *argon2Scheme) verify(hash, password string) (bool, error) {
+	p, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false, err
+	}
+	other := a.idKey([]byte(password), salt, p, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (a *argon2Scheme) weakerThanCurrent(hash string) bool {
+	p, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+	return p.Memory < a.params.Memory || p.Iterations < a.params.Iterations || p.Parallelism < a.params.Parallelism ||
+		uint32(len(salt)) < a.params.SaltLength || uint32(len(key)) < a.params.KeyLength
+}
+
+// decodeArgon2 разбирает $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	b64 := base64.RawStdEncoding
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,48 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt учитывает только первые 72 байта
+const bcryptMaxPasswordLength = 72
+
+type bcryptScheme struct {
+	cost int
+}
+
+func (b *bcryptScheme) validate() error {
+	if b.cost < bcrypt.MinCost || b.cost > bcrypt.MaxCost {
+		return errors.New("bcrypt cost is out of range")
+	}
+	return nil
+}
+
+func (b *bcryptScheme) maxPasswordLength() int {
+	return bcryptMaxPasswordLength
+}
+
+func (b *bcryptScheme) matches(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *bcryptScheme) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	return string(hash), err
+}
+
+func (b *bcryptScheme) verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *bcryptScheme) weakerThanCurrent(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < b.cost
+}
@@ -0,0 +1,107 @@
+// Package hasher — хеширование паролей. Параметры алгоритма хранятся в самом хеше
+// ($2a$<cost>$... для bcrypt, $argon2id$v=19$m=..,t=..,p=..$... для argon2id), поэтому
+// старые хеши проверяются при любых текущих настройках и могут быть пересчитаны при входе
+package hasher
+
+import (
+	"auth/config"
+	"errors"
+	"log"
+	"strings"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher хеширует новые пароли текущим алгоритмом и проверяет хеши всех поддерживаемых
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash — хеш сделан другим алгоритмом или с более слабыми параметрами, чем текущие
+	NeedsRehash(hash string) bool
+	// MaxPasswordLength — сколько байт пароля учитывает текущий алгоритм
+	MaxPasswordLength() int
+}
+
+// scheme — один алгоритм
+type scheme interface {
+	hash(password string) (string, error)
+	verify(hash, password string) (bool, error)
+	weakerThanCurrent(hash string) bool
+	matches(hash string) bool
+	maxPasswordLength() int
+}
+
+type hasher struct {
+	current scheme
+	schemes []scheme
+}
+
+func New(algorithm string, bcryptCost int, argon Argon2Params) (Hasher, error) {
+	b := &bcryptScheme{cost: bcryptCost}
+	a := newArgon2Scheme(argon)
+
+	h := &hasher{schemes: []scheme{b, a}}
+	switch algorithm {
+	case AlgorithmBcrypt:
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+		h.current = b
+	case AlgorithmArgon2id:
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+		h.current = a
+	default:
+		return nil, errors.New("unknown password hash algorithm: " + algorithm)
+	}
+	return h, nil
+}
+
+// InitHasher создаёт хешер по настройкам PASSWORD_HASH_ALGORITHM, BCRYPT_COST и ARGON2_*
+func InitHasher() Hasher {
+	cfg := config.Env
+	h, err := New(strings.ToLower(cfg.PasswordHashAlgorithm), cfg.BcryptCost, Argon2Params{
+		Memory:      uint32(max(cfg.Argon2Memory, 0)),
+		Iterations:  uint32(max(cfg.Argon2Iterations, 0)),
+		Parallelism: uint8(min(max(cfg.Argon2Parallelism, 0), 255)),
+		SaltLength:  16,
+		KeyLength:   32,
+
+		MaxConcurrent: cfg.Argon2MaxConcurrent,
+	})
+	if err != nil {
+		panic("failed to configure password hasher: " + err.Error())
+	}
+	log.Printf("[Hasher] Hashing new passwords with %s", cfg.PasswordHashAlgorithm)
+	return h
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.current.hash(password)
+}
+
+func (h *hasher) Verify(hash, password string) (bool, error) {
+	for _, s := range h.schemes {
+		if s.matches(hash) {
+			return s.verify(hash, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	if !h.current.matches(hash) {
+		return true
+	}
+	return h.current.weakerThanCurrent(hash)
+}
+
+func (h *hasher) MaxPasswordLength() int {
+	return h.current.maxPasswordLength()
+}
@@ -0,0 +1,210 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Параметры argon2id для тестов: слабые, чтобы тесты шли быстро
+var testArgon = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func mustNew(t *testing.T, algorithm string, cost int, argon Argon2Params) Hasher {
+	t.Helper()
+	h, err := New(algorithm, cost, argon)
+	if err != nil {
+		t.Fatalf("New(%s): %v", algorithm, err)
+	}
+	return h
+}
+
+func TestHashVerifyRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{AlgorithmBcrypt, "$2a$04$"},
+		{AlgorithmArgon2id, "$argon2id$v=19$m=1024,t=1,p=1$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			h := mustNew(t, tt.algorithm, 4, testArgon)
+
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Fatalf("hash %q does not start with %q", hash, tt.prefix)
+			}
+
+			if ok, err := h.Verify(hash, "correct horse"); !ok || err != nil {
+				t.Fatalf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := h.Verify(hash, "wrong horse"); ok || err != nil {
+				t.Fatalf("Verify(wrong) = %v, %v", ok, err)
+			}
+
+			other, _ := h.Hash("correct horse")
+			if other == hash {
+				t.Fatal("two hashes of the same password are equal: salt is not random")
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsEveryKnownFormat(t *testing.T) {
+	bcryptHash, _ := mustNew(t, AlgorithmBcrypt, 4, testArgon).Hash("secret")
+	argonHash, _ := mustNew(t, AlgorithmArgon2id, 4, testArgon).Hash("secret")
+
+	for _, current := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		h := mustNew(t, current, 5, testArgon)
+		for _, hash := range []string{bcryptHash, argonHash} {
+			if ok, err := h.Verify(hash, "secret"); !ok || err != nil {
+				t.Errorf("%s hasher: Verify(%.12s...) = %v, %v", current, hash, ok, err)
+			}
+		}
+	}
+
+	if _, err := mustNew(t, AlgorithmBcrypt, 4, testArgon).Verify("plaintext", "secret"); err != ErrUnknownHash {
+		t.Fatalf("unknown format: err = %v, want ErrUnknownHash", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcrypt4, _ := mustNew(t, AlgorithmBcrypt, 4, testArgon).Hash("secret")
+	bcrypt6, _ := mustNew(t, AlgorithmBcrypt, 6, testArgon).Hash("secret")
+	argonWeak, _ := mustNew(t, AlgorithmArgon2id, 4, testArgon).Hash("secret")
+
+	stronger := testArgon
+	stronger.Iterations = 2
+	moreMemory := testArgon
+	moreMemory.Memory = 2048
+
+	tests := []struct {
+		name      string
+		algorithm string
+		cost      int
+		argon     Argon2Params
+		hash      string
+		want      bool
+	}{
+		{"bcrypt same cost", AlgorithmBcrypt, 4, testArgon, bcrypt4, false},
+		{"bcrypt lower cost", AlgorithmBcrypt, 6, testArgon, bcrypt4, true},
+		{"bcrypt higher cost", AlgorithmBcrypt, 4, testArgon, bcrypt6, false},
+		{"bcrypt to argon2id", AlgorithmArgon2id, 4, testArgon, bcrypt4, true},
+		{"argon2id to bcrypt", AlgorithmBcrypt, 4, testArgon, argonWeak, true},
+		{"argon2id same params", AlgorithmArgon2id, 4, testArgon, argonWeak, false},
+		{"argon2id more iterations", AlgorithmArgon2id, 4, stronger, argonWeak, true},
+		{"argon2id more memory", AlgorithmArgon2id, 4, moreMemory, argonWeak, true},
+		{"argon2id broken hash", AlgorithmArgon2id, 4, testArgon, "$argon2id$v=19$garbage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mustNew(t, tt.algorithm, tt.cost, tt.argon)
+			if got := h.NeedsRehash(tt.hash); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeArgon2(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		want    Argon2Params
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			hash: "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+			want: Argon2Params{Memory: 65536, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 29},
+		},
+		{name: "wrong variant", hash: "$argon2i$v=19$m=65536,t=3,p=2$c2FsdA$a2V5", wantErr: true},
+		{name: "wrong version", hash: "$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5", wantErr: true},
+		{name: "missing params", hash: "$argon2id$v=19$m=65536,t=3$c2FsdA$a2V5", wantErr: true},
+		{name: "bad salt encoding", hash: "$argon2id$v=19$m=65536,t=3,p=2$!!!$a2V5", wantErr: true},
+		{name: "empty key", hash: "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$", wantErr: true},
+		{name: "too few parts", hash: "$argon2id$v=19$m=65536,t=3,p=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := decodeArgon2(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("params = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		cost      int
+		argon     Argon2Params
+	}{
+		{"unknown algorithm", "md5", 10, testArgon},
+		{"bcrypt cost too low", AlgorithmBcrypt, 3, testArgon},
+		{"bcrypt cost too high", AlgorithmBcrypt, 32, testArgon},
+		{"argon2id no iterations", AlgorithmArgon2id, 10, Argon2Params{Memory: 1024, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		{"argon2id no parallelism", AlgorithmArgon2id, 10, Argon2Params{Memory: 1024, Iterations: 1, SaltLength: 16, KeyLength: 32}},
+		{"argon2id short salt", AlgorithmArgon2id, 10, Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32}},
+		{"argon2id negative concurrency", AlgorithmArgon2id, 10, Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32, MaxConcurrent: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.algorithm, tt.cost, tt.argon); err == nil {
+				t.Fatal("New accepted invalid configuration")
+			}
+		})
+	}
+}
+
+func TestMaxPasswordLength(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      int
+	}{
+		{AlgorithmBcrypt, 72},
+		{AlgorithmArgon2id, 1024},
+	}
+	for _, tt := range tests {
+		if got := mustNew(t, tt.algorithm, 4, testArgon).MaxPasswordLength(); got != tt.want {
+			t.Errorf("%s: MaxPasswordLength = %d, want %d", tt.algorithm, got, tt.want)
+		}
+	}
+}
+
+func TestArgon2ConcurrencyLimit(t *testing.T) {
+	limited := testArgon
+	limited.MaxConcurrent = 2
+	a := newArgon2Scheme(limited)
+
+	// Все слоты заняты — вычисление ждёт, пока один не освободится
+	a.slots <- struct{}{}
+	a.slots <- struct{}{}
+	done := make(chan string)
+	go func() {
+		hash, _ := a.hash("secret")
+		done <- hash
+	}()
+	select {
+	case <-done:
+		t.Fatal("hash computed while all slots were taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-a.slots
+	hash := <-done
+	if ok, err := a.verify(hash, "secret"); !ok || err != nil {
+		t.Fatalf("verify = %v, %v", ok, err)
+	}
+	if len(a.slots) != 1 {
+		t.Fatalf("%d slots taken after hashing, want 1", len(a.slots))
+	}
+}
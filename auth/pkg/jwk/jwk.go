@@ -0,0 +1,184 @@
+package jwk
+
+import (
+	"auth/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey — ключ подписи токенов; kid совпадает с именем PEM-файла
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+}
+
+// Key — публичный ключ в формате JWK (RFC 7517)
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+var (
+	keys   = make(map[string]*SigningKey)
+	active *SigningKey
+)
+
+// ValidMethods — алгоритмы, которые принимаются при проверке токенов
+var ValidMethods = []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+
+// InitKeys загружает все ключи из JWT_KEYS_DIR (*.pem, Ed25519 или RSA).
+// Подписывает активный ключ (JWT_ACTIVE_KID или последний по имени), остальные
+// остаются в JWKS, пока не истекут выданные ими токены — так выполняется ротация.
+// Без JWT_KEYS_DIR сервис не стартует: временный ключ генерируется только при явном JWT_EPHEMERAL_KEYS=true,
+// иначе после рестарта или на другой реплике все выданные токены молча становятся недействительными.
+func InitKeys() {
+	dir := config.Env.JWTKeysDir
+	if dir == "" {
+		if !config.Env.JWTEphemeralKeys {
+			panic("JWT_KEYS_DIR is not set (set JWT_EPHEMERAL_KEYS=true to use a temporary key in development)")
+		}
+		log.Println("[JWK] JWT_KEYS_DIR is not set, generating ephemeral Ed25519 key (dev only)")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			panic("failed to generate signing key: " + err.Error())
+		}
+		addKey("dev", priv)
+		active = keys["dev"]
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil || len(files) == 0 {
+		panic("no signing keys found in " + dir)
+	}
+	sort.Strings(files)
+
+	var lastKID string
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		signer, err := loadPrivateKey(file)
+		if err != nil {
+			panic(fmt.Sprintf("failed to load signing key %s: %v", file, err))
+		}
+		addKey(kid, signer)
+		lastKID = kid
+	}
+
+	activeKID := config.Env.JWTActiveKID
+	if activeKID == "" {
+		activeKID = lastKID
+	}
+	k, ok := keys[activeKID]
+	if !ok {
+		panic("active signing key not found: " + activeKID)
+	}
+	active = k
+	log.Printf("[JWK] Loaded %d signing key(s), active kid: %s", len(keys), active.KID)
+}
+
+func addKey(kid string, signer crypto.Signer) {
+	method := jwt.SigningMethod(jwt.SigningMethodEdDSA)
+	if _, ok := signer.(*rsa.PrivateKey); ok {
+		method = jwt.SigningMethodRS256
+	}
+	keys[kid] = &SigningKey{KID: kid, Method: method, Private: signer}
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case ed25519.PrivateKey:
+			return k, nil
+		case *rsa.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок
+func Sign(claims jwt.Claims) (string, error) {
+	if active == nil {
+		return "", errors.New("signing keys are not initialized")
+	}
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.KID
+	return token.SignedString(active.Private)
+}
+
+// Keyfunc выбирает публичный ключ по kid из заголовка токена
+func Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return k.Private.Public(), nil
+}
+
+// PublicKeySet возвращает все загруженные ключи для /.well-known/jwks.json
+func PublicKeySet() KeySet {
+	set := KeySet{Keys: make([]Key, 0, len(keys))}
+	for _, k := range keys {
+		jwk := Key{Kid: k.KID, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.Private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,15 @@
+package mailer
+
+import "fmt"
+
+// consoleMailer выводит письма в stdout — для локальной разработки
+type consoleMailer struct{}
+
+func NewConsoleMailer() Mailer {
+	return &consoleMailer{}
+}
+
+func (m *consoleMailer) Send(msg Message) error {
+	fmt.Printf("\n*| to: %s\n*| subject: %s\n%s\n\n", msg.To, msg.Subject, msg.Text)
+	return nil
+}
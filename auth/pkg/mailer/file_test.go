@@ -0,0 +1,161 @@
+package mailer
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMailbox разбирает все письма, которые fileMailer положил в dir
+func readMailbox(t *testing.T, dir string) map[string]*mail.Message {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(map[string]*mail.Message, len(entries))
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		msg, err := mail.ReadMessage(f)
+		if err != nil {
+			t.Fatalf("%s is not a valid email: %v", e.Name(), err)
+		}
+		messages[e.Name()] = msg
+	}
+	return messages
+}
+
+// parts возвращает тела частей multipart/alternative по Content-Type
+func parts(t *testing.T, msg *mail.Message) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	bodies := map[string]string{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(p)
+		contentType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+	return bodies
+}
+
+func TestNewFileMailerRequiresDir(t *testing.T) {
+	if _, err := NewFileMailer("", "noreply@example.com"); err == nil {
+		t.Fatal("NewFileMailer accepted an empty directory")
+	}
+}
+
+func TestFileMailerSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		to      string
+		file    string
+		subject string
+		text    string
+	}{
+		{"russian", "ru-RU", "user@example.com", "user_at_example.com", "Код подтверждения", "Ваш код подтверждения: 123456"},
+		{"english", "en-US", "user@example.com", "user_at_example.com", "Verification code", "Your verification code: 123456"},
+		{"unknown language", "de-DE", "user@example.com", "user_at_example.com", "Код подтверждения", "123456"},
+		{"slash in address", "en-US", "a/b@example.com", "a_b_at_example.com", "Verification code", "123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "mailbox") // каталог создаётся при инициализации
+			m, err := NewFileMailer(dir, "noreply@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg, err := OTPMessage(tt.to, tt.lang, "123456", time.Now().Add(10*time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			mailbox := readMailbox(t, dir)
+			if len(mailbox) != 1 {
+				t.Fatalf("mailbox has %d messages, want 1", len(mailbox))
+			}
+			for name, eml := range mailbox {
+				if !strings.HasSuffix(name, "-"+tt.file+".eml") {
+					t.Errorf("file name %q does not end with -%s.eml", name, tt.file)
+				}
+				if got := eml.Header.Get("From"); got != "noreply@example.com" {
+					t.Errorf("From = %q", got)
+				}
+				if got := eml.Header.Get("To"); got != tt.to {
+					t.Errorf("To = %q, want %q", got, tt.to)
+				}
+				subject, err := new(mime.WordDecoder).DecodeHeader(eml.Header.Get("Subject"))
+				if err != nil || subject != tt.subject {
+					t.Errorf("Subject = %q, %v, want %q", subject, err, tt.subject)
+				}
+
+				bodies := parts(t, eml)
+				if !strings.Contains(bodies["text/plain"], tt.text) {
+					t.Errorf("text part does not contain %q:\n%s", tt.text, bodies["text/plain"])
+				}
+				if !strings.Contains(bodies["text/html"], "123456") {
+					t.Errorf("html part does not contain the code:\n%s", bodies["text/html"])
+				}
+			}
+		})
+	}
+}
+
+func TestFileMailerKeepsEveryMessage(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := "https://example.com/auth/magic?token=a&b"
+	for _, to := range []string{"first@example.com", "second@example.com", "first@example.com"} {
+		msg, err := MagicLinkMessage(to, "ru-RU", link, time.Now().Add(15*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mailbox := readMailbox(t, dir)
+	if len(mailbox) != 3 {
+		t.Fatalf("mailbox has %d messages, want 3", len(mailbox))
+	}
+	for name, eml := range mailbox {
+		bodies := parts(t, eml)
+		if !strings.Contains(bodies["text/plain"], link) {
+			t.Errorf("%s: text part does not contain the link", name)
+		}
+		// В HTML ссылка экранируется шаблоном
+		if !strings.Contains(bodies["text/html"], "token=a&amp;b") {
+			t.Errorf("%s: html part does not contain the escaped link", name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"auth/config"
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"time"
+)
+
+// Mailer — канал доставки писем (OTP-коды и т.п.)
+type Mailer interface {
+	Send(msg Message) error
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// InitMailer выбирает реализацию по MAIL_DRIVER: smtp, file или console (по умолчанию)
+func InitMailer() Mailer {
+	cfg := config.Env
+	switch cfg.MailDriver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+	case "file":
+		m, err := NewFileMailer(cfg.MailDir, cfg.MailFrom)
+		if err != nil {
+			panic("failed to init file mailer: " + err.Error())
+		}
+		return m
+	case "", "console":
+		return NewConsoleMailer()
+	default:
+		panic("unknown MAIL_DRIVER: " + cfg.MailDriver)
+	}
+}
+
+// buildMIME собирает письмо multipart/alternative (text + html)
+func buildMIME(from string, msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.content == "" {
+			continue
+		}
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
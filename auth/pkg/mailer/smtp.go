@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+)
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, user, password, from string) Mailer {
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	return &smtpMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *smtpMailer) Send(msg Message) error {
+	raw, err := buildMIME(m.from, msg)
+	if err != nil {
+		return err
+	}
+	// STARTTLS включается автоматически, если сервер его поддерживает
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, raw)
+}
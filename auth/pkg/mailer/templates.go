@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*
+var templatesFS embed.FS
+
+const DefaultLanguage = "ru-RU"
+
+var subjects = map[string]map[string]string{
+	"otp": {
+		"ru-RU": "Код подтверждения",
+		"en-US": "Verification code",
+	},
+	"magic_link": {
+		"ru-RU": "Вход в аккаунт",
+		"en-US": "Sign in to your account",
+	},
+	"new_device": {
+		"ru-RU": "Вход с нового устройства",
+		"en-US": "New sign-in to your account",
+	},
+}
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templatesFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templatesFS, "templates/*.html"))
+)
+
+// Language приводит заголовок Accept-Language к одному из поддерживаемых языков (ru-RU, en-US)
+func Language(acceptLanguage string) string {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(acceptLanguage)), "en") {
+		return "en-US"
+	}
+	return DefaultLanguage
+}
+
+// OTPMessage формирует письмо с OTP-кодом на нужном языке
+func OTPMessage(to, lang, code string, expiresAt time.Time) (Message, error) {
+	data := struct {
+		Code      string
+		ExpiresAt string
+	}{
+		Code:      code,
+		ExpiresAt: expiresAt.Format("15:04 02.01.2006 MST"),
+	}
+	return render(to, "otp", lang, data)
+}
+
+// MagicLinkMessage формирует письмо со ссылкой для входа без пароля
+func MagicLinkMessage(to, lang, link string, expiresAt time.Time) (Message, error) {
+	data := struct {
+		Link      string
+		ExpiresAt string
+	}{
+		Link:      link,
+		ExpiresAt: expiresAt.Format("15:04 02.01.2006 MST"),
+	}
+	return render(to, "magic_link", lang, data)
+}
+
+// NewDeviceMessage предупреждает о входе с устройства или IP, которых раньше не было
+func NewDeviceMessage(to, lang, device, ip string, at time.Time) (Message, error) {
+	data := struct {
+		Device string
+		IP     string
+		Time   string
+	}{
+		Device: device,
+		IP:     ip,
+		Time:   at.Format("15:04 02.01.2006 MST"),
+	}
+	return render(to, "new_device", lang, data)
+}
+
+func render(to, name, lang string, data interface{}) (Message, error) {
+	if _, ok := subjects[name][lang]; !ok {
+		lang = DefaultLanguage
+	}
+	file := name + "." + lang
+
+	var text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, file+".txt", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, file+".html", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: subjects[name][lang],
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,92 @@
+package oauth
+
+import (
+	"auth/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type Provider struct {
+	Name        string
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+// UserInfo — стандартные claims OIDC из userinfo-эндпоинта
+type UserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func InitProviders() map[string]*Provider {
+	providers := make(map[string]*Provider)
+	for _, p := range config.Env.OAuthProviders {
+		providers[p.Name] = &Provider{
+			Name: p.Name,
+			config: &oauth2.Config{
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  p.RedirectURL,
+				Scopes:       p.Scopes,
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  p.AuthURL,
+					TokenURL: p.TokenURL,
+				},
+			},
+			userInfoURL: p.UserInfoURL,
+		}
+		log.Printf("[OAuth] Provider %s enabled", p.Name)
+	}
+	return providers
+}
+
+// AuthCodeURL строит ссылку на провайдера с PKCE (S256)
+func (p *Provider) AuthCodeURL(state, verifier string) string {
+	return p.config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange меняет код на токен и запрашивает данные пользователя
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*UserInfo, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.config.Client(ctx, token).Get(p.userInfoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo request failed with status %d", resp.StatusCode)
+	}
+
+	var claims struct {
+		Sub           string      `json:"sub"`
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"` // у части провайдеров приходит строкой
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	if claims.Sub == "" {
+		return nil, errors.New("userinfo response has no subject")
+	}
+
+	return &UserInfo{
+		Subject:       claims.Sub,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+	}, nil
+}
@@ -0,0 +1,23 @@
+package passkey
+
+import (
+	"auth/config"
+	"log"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// InitWebAuthn настраивает проверяющую сторону (RP) для церемоний WebAuthn
+func InitWebAuthn() *webauthn.WebAuthn {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          config.Env.WebAuthnRPID,
+		RPDisplayName: config.Env.WebAuthnRPName,
+		RPOrigins:     config.Env.WebAuthnOrigins,
+	})
+	if err != nil {
+		panic("failed to configure WebAuthn: " + err.Error())
+	}
+
+	log.Printf("[WebAuthn] RP %q, origins %v", config.Env.WebAuthnRPID, config.Env.WebAuthnOrigins)
+	return wa
+}
@@ -0,0 +1,73 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+)
+
+// Список утёкших паролей хранится как SHA-1 (формат дампов Have I Been Pwned: HASH или HASH:COUNT в строке)
+// и индексируется по первым 5 символам хеша: проверка смотрит только диапазон с тем же префиксом (k-anonymity),
+// так что тот же код подходит и для запроса диапазонов у внешнего сервиса
+
+//go:embed breached.txt
+var bundledList []byte
+
+const prefixLength = 5
+
+type BreachedList struct {
+	ranges map[string]map[string]struct{} // префикс -> суффиксы
+}
+
+// LoadBreachedList читает файл path; если путь пустой — список, встроенный в сервис
+func LoadBreachedList(path string) (*BreachedList, error) {
+	if path == "" {
+		return parseBreachedList(bytes.NewReader(bundledList))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBreachedList(f)
+}
+
+func parseBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		hash = strings.ToUpper(hash)
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = make(map[string]struct{})
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+	return list, scanner.Err()
+}
+
+// Contains сообщает, есть ли пароль в списке
+func (l *BreachedList) Contains(password string) bool {
+	if l == nil {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := l.Range(hash[:prefixLength])[hash[prefixLength:]]
+	return found
+}
+
+// Range возвращает суффиксы всех хешей с данным префиксом
+func (l *BreachedList) Range(prefix string) map[string]struct{} {
+	return l.ranges[strings.ToUpper(prefix)]
+}
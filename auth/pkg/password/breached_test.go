@@ -0,0 +1,88 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+// SHA-1 от "password" и "123456"
+const (
+	sha1Password = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	sha1Numbers  = "7C4A8D09CA3762AF61E59520943DC26494F8941B"
+)
+
+func TestBreachedListContains(t *testing.T) {
+	input := strings.Join([]string{
+		strings.ToLower(sha1Password) + ":3861493", // нижний регистр и счётчик, как в дампах HIBP
+		"  " + sha1Numbers + "  ",
+		"not a hash",
+		"",
+		"ABCDEF:12",
+	}, "\n")
+	list, err := parseBreachedList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"123456", true},
+		{"Password", false},
+		{"correct horse battery staple", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			if got := list.Contains(tt.password); got != tt.want {
+				t.Fatalf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedListRange(t *testing.T) {
+	list, err := parseBreachedList(strings.NewReader(sha1Password + "\n" + sha1Numbers))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   int
+	}{
+		{"5BAA6", 1},
+		{"5baa6", 1}, // префикс без учёта регистра
+		{"7C4A8", 1},
+		{"00000", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got := list.Range(tt.prefix)
+			if len(got) != tt.want {
+				t.Fatalf("Range(%q) has %d suffixes, want %d", tt.prefix, len(got), tt.want)
+			}
+		})
+	}
+	if _, ok := list.Range("5BAA6")[sha1Password[prefixLength:]]; !ok {
+		t.Fatal("suffix of the hash is missing from its range")
+	}
+}
+
+func TestBundledBreachedList(t *testing.T) {
+	list, err := LoadBreachedList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !list.Contains("password") || list.Contains("a practically unique passphrase 93f1") {
+		t.Fatal("bundled list gives unexpected results")
+	}
+}
+
+func TestNilBreachedList(t *testing.T) {
+	var list *BreachedList
+	if list.Contains("password") {
+		t.Fatal("nil list must contain nothing")
+	}
+}
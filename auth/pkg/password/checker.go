@@ -0,0 +1,47 @@
+package password
+
+import (
+	"auth/config"
+	"log"
+)
+
+// Checker объединяет политику и список утёкших паролей; история паролей проверяется в сервисе
+type Checker struct {
+	Policy   Policy
+	Breached *BreachedList
+}
+
+// InitChecker собирает политику из настроек и загружает список утёкших паролей;
+// maxLength — сколько байт пароля учитывает алгоритм хеширования
+func InitChecker(maxLength int) *Checker {
+	cfg := config.Env
+	checker := &Checker{Policy: Policy{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     maxLength,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLetter: cfg.PasswordRequireLetter,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		HistorySize:   cfg.PasswordHistorySize,
+	}}
+
+	if cfg.PasswordBreachedCheck {
+		list, err := LoadBreachedList(cfg.PasswordBreachedFile)
+		if err != nil {
+			panic("failed to load breached password list: " + err.Error())
+		}
+		checker.Breached = list
+		log.Printf("[Password] Breached password list loaded (%d hash prefixes)", len(list.ranges))
+	}
+	return checker
+}
+
+// Check возвращает все нарушения политики, включая наличие пароля в утечках
+func (c *Checker) Check(password, email string) []Violation {
+	violations := c.Policy.Check(password, email)
+	if c.Breached.Contains(password) {
+		violations = append(violations, Violation{Code: ViolationBreached})
+	}
+	return violations
+}
@@ -0,0 +1,62 @@
+package password
+
+import "fmt"
+
+var messages = map[string]map[string]string{
+	ViolationTooShort: {
+		"ru-RU": "Пароль должен содержать не менее %d символов",
+		"en-US": "Password must be at least %d characters long",
+	},
+	ViolationTooLong: {
+		"ru-RU": "Пароль должен быть не длиннее %d байт",
+		"en-US": "Password must be at most %d bytes long",
+	},
+	ViolationNoLower: {
+		"ru-RU": "Пароль должен содержать строчную букву",
+		"en-US": "Password must contain a lowercase letter",
+	},
+	ViolationNoUpper: {
+		"ru-RU": "Пароль должен содержать заглавную букву",
+		"en-US": "Password must contain an uppercase letter",
+	},
+	ViolationNoLetter: {
+		"ru-RU": "Пароль должен содержать букву",
+		"en-US": "Password must contain a letter",
+	},
+	ViolationNoDigit: {
+		"ru-RU": "Пароль должен содержать цифру",
+		"en-US": "Password must contain a digit",
+	},
+	ViolationNoSymbol: {
+		"ru-RU": "Пароль должен содержать спецсимвол",
+		"en-US": "Password must contain a special character",
+	},
+	ViolationContainsEmail: {
+		"ru-RU": "Пароль не должен содержать email",
+		"en-US": "Password must not contain your email",
+	},
+	ViolationReused: {
+		"ru-RU": "Пароль совпадает с одним из последних %d паролей",
+		"en-US": "Password matches one of your last %d passwords",
+	},
+	ViolationBreached: {
+		"ru-RU": "Этот пароль встречается в утечках данных, выберите другой",
+		"en-US": "This password has appeared in a data breach, choose another one",
+	},
+}
+
+// Message возвращает текст нарушения на языке lang (ru-RU или en-US)
+func (v Violation) Message(lang string) string {
+	texts, ok := messages[v.Code]
+	if !ok {
+		return v.Code
+	}
+	text, ok := texts[lang]
+	if !ok {
+		text = texts["ru-RU"]
+	}
+	if v.Param != 0 {
+		return fmt.Sprintf(text, v.Param)
+	}
+	return text
+}
@@ -0,0 +1,98 @@
+// Package password — проверка новых паролей: длина, классы символов, совпадение с email и утёкшие пароли
+package password
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Коды нарушений; тексты для пользователя — в messages.go
+const (
+	ViolationTooShort      = "too_short"
+	ViolationTooLong       = "too_long"
+	ViolationNoLower       = "no_lower"
+	ViolationNoUpper       = "no_upper"
+	ViolationNoLetter      = "no_letter"
+	ViolationNoDigit       = "no_digit"
+	ViolationNoSymbol      = "no_symbol"
+	ViolationContainsEmail = "contains_email"
+	ViolationReused        = "reused"
+	ViolationBreached      = "breached"
+)
+
+// Violation — одно нарушение политики; Param подставляется в текст (например, минимальная длина)
+type Violation struct {
+	Code  string
+	Param int
+}
+
+type Policy struct {
+	MinLength     int
+	MaxLength     int // в байтах, задаётся алгоритмом хеширования; 0 — без ограничения
+	RequireLower  bool
+	RequireUpper  bool
+	RequireLetter bool
+	RequireDigit  bool
+	RequireSymbol bool
+	HistorySize   int // сколько последних паролей нельзя использовать повторно (включая текущий)
+}
+
+// Check проверяет пароль без обращения к истории и списку утёкших паролей; email может быть пустым
+func (p *Policy) Check(password, email string) []Violation {
+	var violations []Violation
+
+	if length := len([]rune(password)); length < p.MinLength {
+		violations = append(violations, Violation{Code: ViolationTooShort, Param: p.MinLength})
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, Violation{Code: ViolationTooLong, Param: p.MaxLength})
+	}
+
+	var hasLower, hasUpper, hasLetter, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+			hasLower = hasLower || unicode.IsLower(r)
+			hasUpper = hasUpper || unicode.IsUpper(r)
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLetter && !hasLetter {
+		violations = append(violations, Violation{Code: ViolationNoLetter})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Code: ViolationNoLower})
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Code: ViolationNoUpper})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Code: ViolationNoDigit})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Code: ViolationNoSymbol})
+	}
+
+	if containsEmail(password, email) {
+		violations = append(violations, Violation{Code: ViolationContainsEmail})
+	}
+	return violations
+}
+
+// containsEmail — пароль содержит адрес или его часть до @ (короткие части не считаются)
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+	lower := strings.ToLower(password)
+	email = strings.ToLower(email)
+	if strings.Contains(lower, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len([]rune(local)) >= 3 && strings.Contains(lower, local)
+}
@@ -0,0 +1,65 @@
+package password
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) []string {
+	result := []string{}
+	for _, v := range violations {
+		result = append(result, v.Code)
+	}
+	return result
+}
+
+func TestPolicyCheck(t *testing.T) {
+	defaults := Policy{MinLength: 8, MaxLength: 72, RequireLetter: true, RequireDigit: true}
+	unlimited := Policy{MinLength: 8, RequireLetter: true, RequireDigit: true}
+	strict := Policy{MinLength: 8, RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		email    string
+		want     []string
+	}{
+		{"valid", defaults, "river42stone", "", []string{}},
+		{"too short", defaults, "ab1", "", []string{ViolationTooShort}},
+		{"length counts runes", defaults, "пароль12", "", []string{}},
+		{"max length", defaults, strings.Repeat("a1", 36), "", []string{}},
+		{"too long", defaults, strings.Repeat("a1", 37), "", []string{ViolationTooLong}},
+		{"length limit counts bytes", defaults, strings.Repeat("п1", 25), "", []string{ViolationTooLong}},
+		{"no length limit", unlimited, strings.Repeat("a1", 512), "", []string{}},
+		{"no digit", defaults, "riverstone", "", []string{ViolationNoDigit}},
+		{"no letter", defaults, "1234567890", "", []string{ViolationNoLetter}},
+		{"strict valid", strict, "River42-stone", "", []string{}},
+		{"strict no upper and symbol", strict, "river42stone", "", []string{ViolationNoUpper, ViolationNoSymbol}},
+		{"strict no lower", strict, "RIVER42-STONE", "", []string{ViolationNoLower}},
+		{"space counts as symbol", strict, "River 42 stone", "", []string{}},
+		{"contains email", defaults, "x1John.Doe@Example.com", "john.doe@example.com", []string{ViolationContainsEmail}},
+		{"contains local part", defaults, "johndoe2024", "JohnDoe@example.com", []string{ViolationContainsEmail}},
+		{"short local part ignored", defaults, "abstract42", "ab@example.com", []string{}},
+		{"empty email", defaults, "river42stone", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(tt.policy.Check(tt.password, tt.email))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckParams(t *testing.T) {
+	violations := (&Policy{MinLength: 12}).Check("short", "")
+	if len(violations) != 1 || violations[0].Param != 12 {
+		t.Fatalf("violations = %+v, want too_short with Param 12", violations)
+	}
+	if msg := violations[0].Message("en-US"); !strings.Contains(msg, "12") {
+		t.Fatalf("message %q does not mention the minimum length", msg)
+	}
+}
@@ -3,9 +3,11 @@ package rabbitmq
 import (
 	"auth/config"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -53,6 +55,22 @@ func Publish(event string, payload []byte) error {
 	)
 }
 
+// PublishUserEvent публикует событие в очередь user.events
+func PublishUserEvent(userID uuid.UUID, action string, extra map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"user_id": userID,
+		"action":  action,
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return Publish("user.events", payloadBytes)
+}
+
 func Close() {
 	if ch != nil {
 		_ = ch.Close()
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// APITokenPrefix отличает персональные токены от JWT в заголовке Authorization
+const APITokenPrefix = "lcp_"
+
+// Разрешения персональных токенов: <сервис>:read — GET-запросы, <сервис>:write — остальные
+var APITokenScopes = []string{"auth:read", "user:read", "user:write", "chat:read", "chat:write"}
+
+func GenerateAPIToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+func ValidAPITokenScope(scope string) bool {
+	for _, s := range APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail приводит адрес к виду, в котором он хранится в БД: без пробелов по краям и в нижнем регистре
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
-	"auth/config"
+	"auth/pkg/jwk"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -15,9 +17,9 @@ func GenerateTempToken(userID uuid.UUID, duration time.Duration, action string)
 		"id":     userID.String(),
 		"action": action,
 		"exp":    time.Now().Add(duration).Unix(),
+		"jti":    uuid.New().String(), // для одноразовых токенов
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(config.Env.JWTSecret)
+	tokenString, err := jwk.Sign(claims)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Ошибка генерации токена, повторите попытку: %v", err))
 	}
@@ -25,12 +27,7 @@ func GenerateTempToken(userID uuid.UUID, duration time.Duration, action string)
 }
 
 func ValidateTempToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return config.Env.JWTSecret, nil
-	})
+	token, err := jwt.Parse(tokenString, jwk.Keyfunc, jwt.WithValidMethods(jwk.ValidMethods))
 	if err != nil || !token.Valid {
 		return nil, err
 	}
@@ -45,7 +42,11 @@ func ParseToken(tokenString string) (*jwt.Token, error) {
 	if tokenString == "" {
 		return nil, errors.New("token is empty")
 	}
-	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		return config.Env.JWTSecret, nil
-	})
+	return jwt.Parse(tokenString, jwk.Keyfunc, jwt.WithValidMethods(jwk.ValidMethods))
+}
+
+// HashToken — SHA-256 для хранения refresh-токенов в БД
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,239 @@
+// Package authclient — клиент auth-сервиса для других сервисов платформы:
+// проверка токенов через introspection (с кешем) и получение сервисного токена по client credentials.
+package authclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Типы токенов в ответе introspection
+const (
+	TokenTypeAccess   = "access"    // access-токен пользовательской сессии
+	TokenTypeAPIToken = "api_token" // персональный токен (lcp_...)
+	TokenTypeService  = "service"   // токен сервиса, выданный по client credentials
+)
+
+// APITokenPrefix — начало персональных токенов; всё остальное считается JWT
+const APITokenPrefix = "lcp_"
+
+type Config struct {
+	BaseURL      string // адрес auth-сервиса, например http://localhost:8001
+	ClientID     string
+	ClientSecret string
+
+	CacheTTL   time.Duration // сколько помнить ответ introspection; по умолчанию 30 секунд
+	CacheSize  int           // сколько ответов помнить; по умолчанию 10000
+	HTTPClient *http.Client
+}
+
+// Introspection — ответ POST /api/auth/introspect
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Scope     string `json:"scope,omitempty"` // через пробел
+	SessionID string `json:"sid,omitempty"`
+	Role      string `json:"role,omitempty"` // только для access-токенов
+	TokenType string `json:"token_type,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Revoked   bool   `json:"revoked"`
+}
+
+func (i *Introspection) HasScope(scope string) bool {
+	for _, s := range strings.Fields(i.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type cachedIntrospection struct {
+	result    *Introspection
+	expiresAt time.Time
+}
+
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedIntrospection
+
+	keysMu        sync.RWMutex
+	keys          map[string]interface{} // kid -> публичный ключ из JWKS
+	keysFetchedAt time.Time
+	keysAttemptAt time.Time
+
+	tokenMu        sync.Mutex
+	serviceToken   string
+	serviceTokenAt time.Time // когда токен перестанет быть годным
+}
+
+func New(cfg Config) *Client {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = 10000
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &Client{
+		cfg:   cfg,
+		http:  httpClient,
+		cache: make(map[string]cachedIntrospection),
+		keys:  make(map[string]interface{}),
+	}
+}
+
+// ! Introspection
+
+// Introspect проверяет токен в auth-сервисе. JWT сначала проверяются локально по JWKS (подпись и срок),
+// поддельные и просроченные в auth не отправляются. Кешируются только действующие токены — на CacheTTL,
+// но не дольше срока жизни токена, поэтому отзыв становится виден остальным сервисам с задержкой не больше CacheTTL
+func (c *Client) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		if err := c.verifyJWT(ctx, token); err != nil {
+			if errors.Is(err, errInvalidToken) {
+				return &Introspection{}, nil
+			}
+			return nil, err
+		}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	now := time.Now()
+	c.mu.Lock()
+	if cached, ok := c.cache[key]; ok && now.Before(cached.expiresAt) {
+		c.mu.Unlock()
+		return cached.result, nil
+	}
+	c.mu.Unlock()
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/api/auth/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.cfg.ClientID, c.cfg.ClientSecret)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection failed with status %d", resp.StatusCode)
+	}
+
+	var result Introspection
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Отрицательные ответы не кешируем: иначе кеш можно раздуть случайными строками
+	if !result.Active {
+		return &result, nil
+	}
+
+	expiresAt := now.Add(c.cfg.CacheTTL)
+	if result.ExpiresAt > 0 && time.Unix(result.ExpiresAt, 0).Before(expiresAt) {
+		expiresAt = time.Unix(result.ExpiresAt, 0)
+	}
+	c.mu.Lock()
+	c.makeRoom(now)
+	c.cache[key] = cachedIntrospection{result: &result, expiresAt: expiresAt}
+	c.mu.Unlock()
+
+	return &result, nil
+}
+
+// makeRoom освобождает место под новую запись: сначала удаляет истёкшие, а если кеш всё ещё полон —
+// произвольные (порядок обхода map случаен); вызывается под c.mu
+func (c *Client) makeRoom(now time.Time) {
+	if len(c.cache) < c.cfg.CacheSize {
+		return
+	}
+	for k, v := range c.cache {
+		if now.After(v.expiresAt) {
+			delete(c.cache, k)
+		}
+	}
+	for k := range c.cache {
+		if len(c.cache) < c.cfg.CacheSize {
+			return
+		}
+		delete(c.cache, k)
+	}
+}
+
+// ! Client credentials
+
+// ServiceToken возвращает токен этого сервиса для вызовов других сервисов; обновляется заранее, до истечения
+func (c *Client) ServiceToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.serviceToken != "" && time.Now().Before(c.serviceTokenAt) {
+		return c.serviceToken, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/api/auth/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.cfg.ClientID, c.cfg.ClientSecret)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("client credentials grant failed with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("client credentials grant returned no token")
+	}
+
+	lifetime := time.Duration(body.ExpiresIn) * time.Second
+	c.serviceToken = body.AccessToken
+	c.serviceTokenAt = time.Now().Add(lifetime - lifetime/5) // обновляем, когда прошло 80% срока
+	return c.serviceToken, nil
+}
+
+// Do выполняет запрос к другому сервису, подставляя сервисный токен в Authorization
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	token, err := c.ServiceToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.http.Do(req)
+}
@@ -0,0 +1,180 @@
+package authclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAuth — auth-сервис с JWKS и introspection, считающий обращения
+type fakeAuth struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	keys   map[string]ed25519.PrivateKey // kid -> ключ, публикуемый в JWKS
+	status int                           // код ответа introspection
+	active bool
+
+	introspects atomic.Int32
+	jwksFetches atomic.Int32
+}
+
+func newFakeAuth(t *testing.T) *fakeAuth {
+	t.Helper()
+	f := &fakeAuth{keys: map[string]ed25519.PrivateKey{}, status: http.StatusOK, active: true}
+	f.addKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		f.jwksFetches.Add(1)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		set := struct {
+			Keys []jwk `json:"keys"`
+		}{}
+		for kid, key := range f.keys {
+			pub := key.Public().(ed25519.PublicKey)
+			set.Keys = append(set.Keys, jwk{Kty: "OKP", Crv: "Ed25519", Kid: kid, X: base64.RawURLEncoding.EncodeToString(pub)})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("POST /api/auth/introspect", func(w http.ResponseWriter, r *http.Request) {
+		f.introspects.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "chat" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		status, active := f.status, f.active
+		f.mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(Introspection{Active: active, Subject: "user", TokenType: TokenTypeAccess})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAuth) addKey(t *testing.T, kid string) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.keys[kid] = key
+	f.mu.Unlock()
+	return key
+}
+
+func (f *fakeAuth) key(kid string) ed25519.PrivateKey {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.keys[kid]
+}
+
+func (f *fakeAuth) respond(status int, active bool) {
+	f.mu.Lock()
+	f.status, f.active = status, active
+	f.mu.Unlock()
+}
+
+func (f *fakeAuth) client() *Client {
+	return New(Config{BaseURL: f.URL, ClientID: "chat", ClientSecret: "secret"})
+}
+
+// signJWT собирает EdDSA-токен с заданным kid и exp
+func signJWT(t *testing.T, key ed25519.PrivateKey, kid string, exp time.Time) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signed := segment(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": kid}) + "." +
+		segment(map[string]interface{}{"sub": "user", "exp": exp.Unix()})
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(signed)))
+}
+
+func TestIntrospectCachesActiveTokens(t *testing.T) {
+	auth := newFakeAuth(t)
+	c := auth.client()
+	token := signJWT(t, auth.key("key-1"), "key-1", time.Now().Add(15*time.Minute))
+
+	for i := 0; i < 3; i++ {
+		result, err := c.Introspect(context.Background(), token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Active {
+			t.Fatalf("call %d: token is not active", i+1)
+		}
+	}
+	if n := auth.introspects.Load(); n != 1 {
+		t.Fatalf("introspect called %d times, want 1", n)
+	}
+}
+
+func TestIntrospectCacheExpires(t *testing.T) {
+	auth := newFakeAuth(t)
+	c := New(Config{BaseURL: auth.URL, ClientID: "chat", ClientSecret: "secret", CacheTTL: 50 * time.Millisecond})
+	token := APITokenPrefix + "abc"
+
+	c.Introspect(context.Background(), token)
+	time.Sleep(60 * time.Millisecond)
+	c.Introspect(context.Background(), token)
+	if n := auth.introspects.Load(); n != 2 {
+		t.Fatalf("introspect called %d times, want 2", n)
+	}
+}
+
+func TestIntrospectDoesNotCacheFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		active  bool
+		wantErr bool
+	}{
+		{"inactive", http.StatusOK, false, false},
+		{"server error", http.StatusInternalServerError, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newFakeAuth(t)
+			auth.respond(tt.status, tt.active)
+			c := auth.client()
+			token := APITokenPrefix + "abc"
+
+			result, err := c.Introspect(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && result.Active {
+				t.Fatal("inactive token reported as active")
+			}
+
+			// Как только auth снова отвечает «активен», ответ виден сразу, без ожидания CacheTTL
+			auth.respond(http.StatusOK, true)
+			result, err = c.Introspect(context.Background(), token)
+			if err != nil || !result.Active {
+				t.Fatalf("second call = %+v, %v, want active", result, err)
+			}
+			if n := auth.introspects.Load(); n != 2 {
+				t.Fatalf("introspect called %d times, want 2", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+module authclient
+
+go 1.25.1
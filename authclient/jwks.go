@@ -0,0 +1,180 @@
+package authclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Локальная проверка JWT по JWKS auth-сервиса: подпись и срок проверяются без сетевого вызова,
+// поэтому мусорные и просроченные токены отсекаются до introspection
+
+const (
+	jwksCacheTTL        = 10 * time.Minute
+	jwksMinRefreshDelay = 30 * time.Second // не чаще, если пришёл токен с неизвестным kid
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// verifyJWT проверяет подпись (EdDSA или RS256) и exp. errInvalidToken — токен точно недействителен,
+// другие ошибки — не удалось получить ключи
+func (c *Client) verifyJWT(ctx context.Context, token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Kid == "" {
+		return errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errInvalidToken
+	}
+
+	key, err := c.publicKey(ctx, header.Kid)
+	if err != nil {
+		return err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch pub := key.(type) {
+	case ed25519.PublicKey:
+		if header.Alg != "EdDSA" || !ed25519.Verify(pub, signed, signature) {
+			return errInvalidToken
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return errInvalidToken
+		}
+	default:
+		return errInvalidToken
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.Exp == 0 || time.Now().Unix() >= claims.Exp {
+		return errInvalidToken
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// publicKey ищет ключ по kid в закешированном JWKS и при необходимости обновляет его
+func (c *Client) publicKey(ctx context.Context, kid string) (interface{}, error) {
+	c.keysMu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.keysFetchedAt) > jwksCacheTTL
+	c.keysMu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := c.refreshKeys(ctx); err != nil && !ok {
+		return nil, err
+	}
+
+	c.keysMu.RLock()
+	defer c.keysMu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errInvalidToken
+}
+
+func (c *Client) refreshKeys(ctx context.Context) error {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+	if time.Since(c.keysAttemptAt) < jwksMinRefreshDelay && time.Since(c.keysFetchedAt) <= jwksCacheTTL {
+		return nil
+	}
+	c.keysAttemptAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/.well-known/jwks.json", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	parsed := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		parsed[k.Kid] = pub
+	}
+	c.keys = parsed
+	c.keysFetchedAt = time.Now()
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,76 @@
+package authclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnknownKidRefreshesJWKS(t *testing.T) {
+	auth := newFakeAuth(t)
+	c := auth.client()
+	ctx := context.Background()
+
+	if result, err := c.Introspect(ctx, signJWT(t, auth.key("key-1"), "key-1", time.Now().Add(time.Minute))); err != nil || !result.Active {
+		t.Fatalf("key-1 token = %+v, %v", result, err)
+	}
+
+	// Ротация ключа: токен с новым kid приходит раньше, чем истёк кеш JWKS
+	rotated := signJWT(t, auth.addKey(t, "key-2"), "key-2", time.Now().Add(time.Minute))
+
+	// Сразу после загрузки JWKS повторный запрос не делается, неизвестный kid отклоняется
+	if result, err := c.Introspect(ctx, rotated); err != nil || result.Active {
+		t.Fatalf("key-2 token within jwksMinRefreshDelay = %+v, %v, want inactive", result, err)
+	}
+	if n := auth.jwksFetches.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times within jwksMinRefreshDelay, want 1", n)
+	}
+
+	c.keysMu.Lock()
+	c.keysAttemptAt = time.Now().Add(-jwksMinRefreshDelay)
+	c.keysMu.Unlock()
+
+	if result, err := c.Introspect(ctx, rotated); err != nil || !result.Active {
+		t.Fatalf("key-2 token after refresh = %+v, %v, want active", result, err)
+	}
+	if n := auth.jwksFetches.Load(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", n)
+	}
+}
+
+func TestInvalidJWTRejectedLocally(t *testing.T) {
+	auth := newFakeAuth(t)
+	_, foreign, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := signJWT(t, auth.key("key-1"), "key-1", time.Now().Add(time.Minute))
+	dot := strings.LastIndex(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"foreign key", signJWT(t, foreign, "key-1", time.Now().Add(time.Minute))},
+		{"tampered signature", valid[:dot+1] + strings.Repeat("A", len(valid)-dot-1)},
+		{"expired", signJWT(t, auth.key("key-1"), "key-1", time.Now().Add(-time.Second))},
+		{"not a JWT", "garbage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := auth.client().Introspect(context.Background(), tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Active {
+				t.Fatal("invalid token reported as active")
+			}
+		})
+	}
+	if n := auth.introspects.Load(); n != 0 {
+		t.Fatalf("introspect called %d times for invalid tokens, want 0", n)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"chat/config"
 	"chat/internal/router"
+	"chat/pkg/auth"
 	chatdb "chat/pkg/database"
 	"chat/pkg/redis"
 	"context"
@@ -19,6 +20,7 @@ import (
 func main() {
 	config.InitEnv()
 	redis.InitChatRedis()
+	auth.InitAuthClient()
 	chatdb.InitDB()
 	r := router.InitRouter()
 
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"chat/config"
+
+	"authclient"
+)
+
+// Client проверяет токены пользователей в auth-сервисе и выдаёт токен этого сервиса для внутренних вызовов
+var Client *authclient.Client
+
+func InitAuthClient() *authclient.Client {
+	Client = authclient.New(authclient.Config{
+		BaseURL:      config.Env.AuthURL,
+		ClientID:     config.Env.ServiceClientID,
+		ClientSecret: config.Env.ServiceClientSecret,
+	})
+	return Client
+}
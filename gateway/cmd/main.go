@@ -11,10 +11,6 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// TODO:
-// 1. Изменение пароля
-// 2. 2FA (TOTP || Google Authenticator)
-
 func main() {
 	config.InitEnv()
 	r := gin.Default()
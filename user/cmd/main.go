@@ -23,6 +23,7 @@ import (
 	"user/internal/middleware"
 	"user/internal/repository"
 	"user/internal/service"
+	"user/pkg/auth"
 	userdb "user/pkg/database"
 	"user/pkg/rabbitmq"
 	"user/pkg/redis"
@@ -37,6 +38,7 @@ import (
 func main() {
 	config.InitEnv()
 	redis.InitUserRedis()
+	auth.InitAuthClient()
 	userdb.InitDB()
 	rabbitmq.InitRabbitMQ()
 
@@ -2,12 +2,15 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	JWTSecret []byte
+	AuthURL             string
+	ServiceClientID     string
+	ServiceClientSecret string
 
 	DBHost     string
 	DBPort     string
@@ -28,8 +31,11 @@ func InitEnv() {
 		panic(".env file not found")
 	}
 
+	clientID := getString("SERVICE_CLIENT_ID", "user")
 	Env = &Config{
-		JWTSecret: []byte(os.Getenv("JWT_SECRET")),
+		AuthURL:             getRequired("AUTH_URL"),
+		ServiceClientID:     clientID,
+		ServiceClientSecret: getClientSecret(clientID),
 
 		DBHost:     os.Getenv("DB_HOST"),
 		DBPort:     os.Getenv("DB_PORT"),
@@ -43,3 +49,28 @@ func InitEnv() {
 		RedisAddr:    os.Getenv("REDIS_ADDR"),
 	}
 }
+
+// getRequired — без этих значений сервис не сможет проверять токены в auth, поэтому не стартуем
+func getRequired(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		panic(key + " is not set")
+	}
+	return value
+}
+
+// getClientSecret берёт SERVICE_CLIENT_SECRET, а если его нет — SERVICE_CLIENT_<ID>_SECRET из общего .env,
+// тот же, что читает auth: так у каждого сервиса свой секрет
+func getClientSecret(clientID string) string {
+	if value := os.Getenv("SERVICE_CLIENT_SECRET"); value != "" {
+		return value
+	}
+	return getRequired("SERVICE_CLIENT_" + strings.ToUpper(clientID) + "_SECRET")
+}
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,37 @@
+package consumer
+
+import (
+	"log"
+	"time"
+	"user/internal/models"
+	"user/pkg/utils"
+	"user/pkg/websocket"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// handleUserDeleted удаляет профиль, настройки и блокировки (в обе стороны) удалённого аккаунта
+func handleUserDeleted(db *gorm.DB, userID uuid.UUID) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("profile_id = ? OR blocked_profile_id = ?", userID, userID).Delete(&models.Block{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("profile_id = ?", userID).Delete(&models.Settings{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", userID).Delete(&models.Profile{}).Error
+	})
+	if err != nil {
+		log.Printf("Ошибка. Не удалось удалить профиль %s: %v", userID, err)
+		return
+	}
+
+	websocket.Remove(userID, "account deleted")
+
+	// Последний статус, чтобы у собеседников пользователь сразу стал offline
+	if err := utils.PublishStatusEvent(userID, false, time.Now().String()); err != nil {
+		log.Printf("[UserDeleted] Failed to publish offline event for %s: %v", userID, err)
+	}
+	log.Printf("Profile %s deleted", userID)
+}
@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"user/pkg/rabbitmq"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func StartUserEventsConsumer(db *gorm.DB) {
+	err := rabbitmq.Consume("user.events", func(body []byte) {
+		var event struct {
+			UserID string `json:"user_id"`
+			Action string `json:"action"`
+			Name   string `json:"name"` // имя бота, для обычных пользователей пусто
+		}
+		if err := json.Unmarshal(body, &event); err != nil {
+			fmt.Printf("Invalid event JSON: %v", err)
+			return
+		}
+		userID, err := uuid.Parse(event.UserID)
+		if err != nil {
+			log.Printf("Invalid user_id in %s event: %v", event.Action, err)
+			return
+		}
+
+		switch event.Action {
+		case "user_created":
+			handleUserCreated(db, userID, event.Name)
+		case "user_deleted":
+			handleUserDeleted(db, userID)
+		case "user_suspended":
+			handleUserSuspended(userID)
+		default:
+			return // игнорируем другие события
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to start consumer: %v", err)
+	}
+	log.Println("User events consumer started")
+
+}
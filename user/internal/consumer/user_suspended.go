@@ -0,0 +1,21 @@
+package consumer
+
+import (
+	"log"
+	"time"
+	"user/pkg/utils"
+	"user/pkg/websocket"
+
+	"github.com/google/uuid"
+)
+
+// handleUserSuspended закрывает WebSocket заблокированного пользователя; новые подключения отклоняет AuthMiddleware,
+// пока в Redis есть отметка auth:suspended:<id>
+func handleUserSuspended(userID uuid.UUID) {
+	websocket.Disconnect(userID, "account suspended")
+
+	if err := utils.PublishStatusEvent(userID, false, time.Now().String()); err != nil {
+		log.Printf("[UserSuspended] Failed to publish offline event for %s: %v", userID, err)
+	}
+	log.Printf("User %s suspended, WebSocket closed", userID)
+}
@@ -113,7 +113,10 @@ func readPong(c *websocket.Client, r *repository.ProfileRepository) {
 		delete(websocket.Clients, c.UserID)
 		websocket.ClientsMu.Unlock()
 
-		utils.SetOffline(c.UserID, r)
+		// Профиль удалённого аккаунта уже удалён, offline опубликован в handleUserDeleted
+		if !c.Removed.Load() {
+			utils.SetOffline(c.UserID, r)
+		}
 
 		_ = c.Conn.Close()
 		log.Printf("User %s disconnected", c.UserID)
@@ -146,6 +149,12 @@ func writePing(c *websocket.Client) {
 	for {
 		select {
 		case <-ticker.C:
+			// Страховка на случай потерянного события user_suspended
+			suspended, _ := redis.UserRedis.Exists(context.Background(), "auth:suspended:"+c.UserID.String()).Result()
+			if suspended > 0 {
+				websocket.Disconnect(c.UserID, "account suspended")
+				return
+			}
 			c.Mu.Lock()
 			if err := c.Conn.WriteControl(ws.PingMessage, []byte("ping"), time.Now().Add(10*time.Second)); err != nil {
 				log.Printf("Ping failed for user %s: %v", c.UserID, err)
@@ -1,51 +1,97 @@
 package middleware
 
 import (
-	"errors"
 	"net/http"
-	"user/config"
+	"strings"
+	"user/pkg/auth"
 	"user/pkg/redis"
 
+	"authclient"
+
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// AuthMiddleware принимает access-токен из cookie или заголовка Authorization: Bearer, а также персональные токены (lcp_...).
+// Подпись и срок JWT проверяются локально по JWKS, затем токен проверяется в auth-сервисе (introspection),
+// поэтому отзыв сессии или токена виден здесь с задержкой не больше кеша клиента
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		accessToken, err := c.Cookie("access_token")
-		if err != nil || accessToken == "" {
+		accessToken := bearerToken(c)
+		if accessToken == "" {
+			accessToken, _ = c.Cookie("access_token")
+		}
+		if accessToken == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
 
-		token, err := parseToken(accessToken)
-		if err != nil || !token.Valid {
+		info, err := auth.Client.Introspect(c.Request.Context(), accessToken)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "auth service unavailable"})
+			return
+		}
+		if info.Revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+		if !info.Active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+
+		switch info.TokenType {
+		case authclient.TokenTypeAccess:
+		case authclient.TokenTypeAPIToken:
+			// Персональный токен: нужен user:read для чтения и user:write для остального
+			scope := "user:write"
+			if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+				scope = "user:read"
+			}
+			if !info.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token lacks scope " + scope})
+				return
+			}
+		default: // сервисные токены не представляют пользователя
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			return
 		}
-		claims, _ := token.Claims.(jwt.MapClaims)
 
-		jti, _ := claims["jti"].(string)
-		if jti != "" {
-			key := "auth:blacklist:access:" + jti
-			exists, _ := redis.UserRedis.Get(c.Request.Context(), key).Result()
-			if exists != "" { // ключ существует -> токен отозван
+		userID, err := uuid.Parse(info.Subject)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		// Блокировка и завершение сессии действуют сразу, не дожидаясь истечения кеша introspection
+		suspended, _ := redis.UserRedis.Exists(c.Request.Context(), "auth:suspended:"+userID.String()).Result()
+		if suspended > 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			return
+		}
+		sessionID, err := uuid.Parse(info.SessionID)
+		if err == nil {
+			revoked, _ := redis.UserRedis.Exists(c.Request.Context(), "auth:session:revoked:"+sessionID.String()).Result()
+			if revoked > 0 {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
 				return
 			}
 		}
 
-		c.Set("userID", uuid.MustParse(claims["id"].(string)))
+		c.Set("userID", userID)
+		if info.Role != "" {
+			c.Set("role", info.Role)
+		}
+		if err == nil {
+			c.Set("sessionID", sessionID)
+		}
 		c.Next()
 	}
 }
 
-func parseToken(tokenString string) (*jwt.Token, error) {
-	if tokenString == "" {
-		return nil, errors.New("token is empty")
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
 	}
-	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		return config.Env.JWTSecret, nil
-	})
+	return ""
 }
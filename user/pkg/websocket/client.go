@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -11,6 +13,8 @@ type Client struct {
 	UserID uuid.UUID
 	Conn   *websocket.Conn
 	Mu     sync.Mutex
+	// Removed — аккаунт удалён: при закрытии соединения не нужно публиковать offline и обновлять last_seen
+	Removed atomic.Bool
 }
 
 var (
@@ -24,3 +28,33 @@ func IsClientOnline(clientID uuid.UUID) bool {
 	ClientsMu.RUnlock()
 	return exists && c.Conn != nil
 }
+
+// Disconnect закрывает соединение пользователя, если он онлайн
+func Disconnect(clientID uuid.UUID, reason string) {
+	disconnect(clientID, reason, false)
+}
+
+// Remove закрывает соединение удалённого аккаунта; статус offline публикует вызывающий
+func Remove(clientID uuid.UUID, reason string) {
+	disconnect(clientID, reason, true)
+}
+
+func disconnect(clientID uuid.UUID, reason string, removed bool) {
+	ClientsMu.Lock()
+	c, exists := Clients[clientID]
+	delete(Clients, clientID)
+	ClientsMu.Unlock()
+	if !exists || c.Conn == nil {
+		return
+	}
+	c.Removed.Store(removed)
+
+	c.Mu.Lock()
+	_ = c.Conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second),
+	)
+	c.Mu.Unlock()
+	_ = c.Conn.Close()
+}